@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"os"
+	"sort"
+	"space-monitor/libs/age"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"time"
+)
+
+// Config_Ages settings of the file age histogram and the stale data report
+type Config_Ages struct {
+	Atime    bool   `yaml:"atime"`     // also collect the histogram by access time (meaningless on noatime mounts)
+	StaleAge string `yaml:"stale-age"` // subtrees with no file modified for this long are stale (default 1y)
+	StaleTop int    `yaml:"stale-top"` // number of the largest stale subtrees kept per directory (default 20)
+}
+
+// Validate checks the age settings
+func (c Config_Ages) Validate() error {
+	if c.StaleAge != "" {
+		if d, err := human.ParseDuration(c.StaleAge); err != nil || d <= 0 {
+			return fmt.Errorf("ages: invalid stale-age %q", c.StaleAge)
+		}
+	}
+	if c.StaleTop < 0 {
+		return fmt.Errorf("ages: invalid stale-top %d", c.StaleTop)
+	}
+	return nil
+}
+
+// StaleDuration returns the age of the newest file making the subtree stale
+func (c Config_Ages) StaleDuration() time.Duration {
+	if d, err := human.ParseDuration(c.StaleAge); err == nil && d > 0 {
+		return d
+	}
+	return 365 * 24 * time.Hour
+}
+
+// TopCount returns the number of the stale subtrees kept per directory
+func (c Config_Ages) TopCount() int {
+	if c.StaleTop > 0 {
+		return c.StaleTop
+	}
+	return 20
+}
+
+// ageCollector collects the age histograms and the stale subtrees during the walk
+type ageCollector struct {
+	now   time.Time
+	atime bool
+	stale *age.StaleTracker
+}
+
+// newAgeCollector creates the collector of the walk of the root started at now (file ages are relative to it)
+func newAgeCollector(root string, now time.Time) *ageCollector {
+	cutoff := now.Add(-gCfg.Ages.StaleDuration())
+	return &ageCollector{now: now, atime: gCfg.Ages.Atime, stale: age.NewStaleTracker(root, cutoff, gCfg.Ages.TopCount())}
+}
+
+// add counts the file in the histograms of the directory info and in the stale subtrees
+func (c *ageCollector) add(info *DirInfoStruct, path string, fileInfo os.FileInfo) {
+	if info.MtimeAges == nil {
+		info.MtimeAges = map[string]UsageStat{}
+	}
+	mtime := fileInfo.ModTime()
+	addUsage(info.MtimeAges, age.BucketOf(c.now.Sub(mtime)), fileInfo.Size())
+	if c.atime {
+		if atime, ok := fileAccessTime(fileInfo); ok {
+			if info.AtimeAges == nil {
+				info.AtimeAges = map[string]UsageStat{}
+			}
+			addUsage(info.AtimeAges, age.BucketOf(c.now.Sub(atime)), fileInfo.Size())
+		}
+	}
+	c.stale.Add(path, fileInfo.Size(), mtime)
+}
+
+// finish stores the largest topmost stale subtrees (their parent is not stale) into the directory info
+func (c *ageCollector) finish(info *DirInfoStruct) {
+	info.Stale, info.StaleSince = c.stale.Finish(), c.now.Add(-gCfg.Ages.StaleDuration())
+}
+
+// printAgeHistogram prints the histogram of the directory by modification (and access) time
+func printAgeHistogram(info DirInfoStruct) {
+	tableWriter := table.NewWriter()
+	tableWriter.SetTitle("file ages of %s", color.HiBlueString(shorifyPath(info.Path)))
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	header := table.Row{"age", "modified", "files", "share"}
+	if len(info.AtimeAges) > 0 {
+		header = append(header, "accessed", "files", "share")
+	}
+	tableWriter.AppendHeader(header)
+
+	var total int64
+	for _, stat := range info.MtimeAges {
+		total += stat.Size
+	}
+	cells := func(stat UsageStat) table.Row {
+		if stat.Files == 0 {
+			return table.Row{"", "", ""}
+		}
+		share := ""
+		if total > 0 {
+			share = fmt.Sprintf("%.1f%%", float64(stat.Size)*100/float64(total))
+		}
+		return table.Row{HumanSize(stat.Size), fmt.Sprint(stat.Files), share}
+	}
+	for _, bucket := range age.Buckets {
+		row := append(table.Row{bucket.Name}, cells(info.MtimeAges[bucket.Name])...)
+		if len(info.AtimeAges) > 0 {
+			row = append(row, cells(info.AtimeAges[bucket.Name])...)
+		}
+		tableWriter.AppendRow(row)
+	}
+	tableWriter.Render()
+}
+
+// CommandStale prints the file age histograms and the largest stale subtrees of the snapshot
+func CommandStale(args []string) int {
+	if len(args) > 1 {
+		LogErr("usage: stale [snapshot]")
+		return 1
+	}
+	ref := "last"
+	if len(args) == 1 {
+		ref = args[0]
+	}
+	snapshot, err := FindSnapshot(ref)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+
+	var stale []StaleSubtree
+	var since time.Time
+	var staleAge time.Duration
+	for _, dir := range gCfg.Dirs {
+		info, err := gStore.LoadDirInfo(snapshot, AbsPath(dir.Path), false)
+		if err != nil {
+			LogErr(err)
+			continue
+		}
+		if len(info.MtimeAges) == 0 {
+			fmt2.Printf(" %s: no file ages in snapshot %s (made by an older version)\n", shorifyPath(info.Path), snapshot.ID)
+			continue
+		}
+		printAgeHistogram(info)
+		stale = append(stale, info.Stale...)
+		since, staleAge = info.StaleSince, info.StartTime.Sub(info.StaleSince)
+	}
+	if since.IsZero() {
+		return 0
+	}
+	if len(stale) == 0 {
+		fmt2.Printf("no subtrees older than %s (no changes since %s)\n", HumanDuration(staleAge), since.Format("2006-01-02"))
+		return 0
+	}
+
+	sort.Slice(stale, func(i, j int) bool { return stale[i].Size > stale[j].Size })
+	if len(stale) > gCfg.Ages.TopCount() {
+		stale = stale[:gCfg.Ages.TopCount()]
+	}
+	tableWriter := table.NewWriter()
+	tableWriter.SetTitle("stale subtrees (no changes since %s)", since.Format("2006-01-02"))
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.AppendHeader(table.Row{"path", "size", "files", "newest file"})
+	var total int64
+	for _, subtree := range stale {
+		total += subtree.Size
+		tableWriter.AppendRow(table.Row{
+			color.HiBlueString(shorifyPath(subtree.Path)),
+			HumanSize(subtree.Size),
+			subtree.Files,
+			subtree.Newest.Format("2006-01-02") + " (" + HumanDuration(snapshot.StartTime.Sub(subtree.Newest)) + " ago)",
+		})
+	}
+	tableWriter.AppendFooter(table.Row{"total", HumanSize(total), "", ""})
+	tableWriter.Render()
+	return 0
+}
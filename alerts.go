@@ -0,0 +1,67 @@
+package main
+
+import (
+	"github.com/fatih/color"
+	"space-monitor/libs/alerting"
+	"space-monitor/libs/fmt2"
+)
+
+type (
+	AlertLevel   = alerting.Level
+	Alert        = alerting.Alert
+	Config_Alert = alerting.Rule
+)
+
+const (
+	AlertOK       = alerting.OK
+	AlertWarning  = alerting.Warning
+	AlertCritical = alerting.Critical
+	AlertUnknown  = alerting.Unknown
+)
+
+// ExitFatal is the exit code of failed runs: UNKNOWN, so Nagios and cron wrappers can tell it from warnings
+const ExitFatal = int(AlertUnknown)
+
+// ParseThreshold parses thresholds like "10G", "5%" or "1000" (with sizes set, the value is a size)
+func ParseThreshold(str string, size bool) (alerting.Threshold, error) {
+	return alerting.ParseThreshold(str, size)
+}
+
+// EvaluateAlerts checks the rules and owner quotas against the current run results
+func EvaluateAlerts(rules []Config_Alert, prev, curr SnapshotStruct, trends Trends) []Alert {
+	rates := alerting.Rates{Dirs: map[string]float64{}}
+	for path, growth := range trends.Dirs {
+		if growth.Valid {
+			rates.Dirs[path] = growth.Rate * day.Seconds()
+		}
+	}
+	if trends.FreeSpace.Valid {
+		rates.Used, rates.UsedValid = trends.FreeSpace.Rate*day.Seconds(), true
+	}
+	alerts := alerting.Evaluate(rules, prev, curr, rates, AbsPath)
+	return append(alerts, QuotaAlerts(curr)...)
+}
+
+// MaxAlertLevel returns the highest level of the alerts (the process exit code)
+func MaxAlertLevel(alerts []Alert) AlertLevel {
+	return alerting.MaxLevel(alerts)
+}
+
+// PrintAlerts prints the alert section
+func PrintAlerts(alerts []Alert) {
+	if len(alerts) == 0 {
+		return
+	}
+	fmt2.Println()
+	fmt2.Println(color.New(color.Bold, color.BgRed, color.FgHiWhite).Sprintf(" ALERTS: %d ", len(alerts)))
+	for _, alert := range alerts {
+		levelColor := color.New(color.Bold, color.FgHiYellow)
+		switch alert.Level {
+		case AlertCritical:
+			levelColor = color.New(color.Bold, color.FgHiRed)
+		case AlertUnknown:
+			levelColor = color.New(color.Bold, color.FgHiMagenta)
+		}
+		fmt2.Printf(" %s %s: %s\n", levelColor.Sprintf("%-8s", alert.Level), Bold("%s", alert.Rule), alert.Message)
+	}
+}
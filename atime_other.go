@@ -0,0 +1,13 @@
+//go:build !linux && !openbsd && !darwin && !freebsd && !netbsd
+
+package main
+
+import (
+	"os"
+	"time"
+)
+
+// fileAccessTime is not supported on this platform
+func fileAccessTime(fileInfo os.FileInfo) (time.Time, bool) {
+	return time.Time{}, false
+}
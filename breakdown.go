@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/category"
+	"space-monitor/libs/fmt2"
+)
+
+// number of the largest extensions shown in the breakdown table
+const breakdownTopExtensions = 10
+
+// number of the largest extensions stored per directory (random suffixes would bloat the dir info)
+const storedTopExtensions = 100
+
+// DefaultCategories are used if the config has no 'categories' section
+var DefaultCategories = map[string][]string{
+	"video":     {"mp4", "mkv", "avi", "mov", "wmv", "webm", "m4v", "mpg", "mpeg", "m2ts"},
+	"images":    {"jpg", "jpeg", "png", "gif", "bmp", "tif", "tiff", "webp", "heic", "raw", "cr2", "nef", "svg"},
+	"audio":     {"mp3", "flac", "wav", "aac", "ogg", "m4a", "wma", "opus"},
+	"archives":  {"zip", "tar", "gz", "tgz", "bz2", "xz", "zst", "7z", "rar"},
+	"logs":      {"log", "*.log.*", "journal"},
+	"vm-images": {"iso", "img", "qcow2", "vdi", "vmdk", "vhd", "vhdx", "ova"},
+	"packages":  {"deb", "rpm", "apk", "msi", "dmg", "pkg", "whl", "jar", "snap"},
+	"documents": {"pdf", "doc", "docx", "xls", "xlsx", "ppt", "pptx", "odt", "ods", "txt", "md"},
+}
+
+var gCategories *category.Matcher
+
+// InitCategories compiles categories of the config (default ones if not configured)
+func InitCategories() error {
+	categories := gCfg.Categories
+	if categories == nil {
+		categories = DefaultCategories
+	}
+	var err error
+	gCategories, err = category.New(categories)
+	return err
+}
+
+// addBreakdown counts the file in the extension and category breakdown of the directory
+func addBreakdown(info *DirInfoStruct, path string, size int64) {
+	if info.Extensions == nil {
+		info.Extensions = map[string]UsageStat{}
+		info.Categories = map[string]UsageStat{}
+	}
+	name := filepath.Base(path)
+	addUsage(info.Extensions, category.Extension(name), size)
+	if gCategories != nil {
+		if name := gCategories.Match(name); name != "" {
+			addUsage(info.Categories, name, size)
+		} else {
+			addUsage(info.Categories, "other", size)
+		}
+	}
+}
+
+// finishBreakdown keeps the largest extensions of the directory info, the rest is summed as other
+func finishBreakdown(info *DirInfoStruct) {
+	info.Extensions = category.Top(info.Extensions, storedTopExtensions)
+}
+
+func addUsage(stats map[string]UsageStat, key string, size int64) {
+	stat := stats[key]
+	stat.Size += size
+	stat.Files++
+	stats[key] = stat
+}
+
+// byCategory and byExtension select the breakdown of the directory info
+func byCategory(info DirInfoStruct) map[string]UsageStat  { return info.Categories }
+func byExtension(info DirInfoStruct) map[string]UsageStat { return info.Extensions }
+
+// SumBreakdown sums the breakdown (selected by the function) of all directories of the snapshot
+func SumBreakdown(snapshot SnapshotStruct, breakdown func(DirInfoStruct) map[string]UsageStat) map[string]UsageStat {
+	total := map[string]UsageStat{}
+	for _, info := range snapshot.InfoList {
+		for key, stat := range breakdown(info) {
+			sum := total[key]
+			sum.Size += stat.Size
+			sum.Files += stat.Files
+			total[key] = sum
+		}
+	}
+	return total
+}
+
+// sortedKeys returns keys of the stats sorted by size (larger first)
+func sortedKeys(stats map[string]UsageStat) []string {
+	var keys []string
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if stats[keys[i]].Size != stats[keys[j]].Size {
+			return stats[keys[i]].Size > stats[keys[j]].Size
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// PrintBreakdown prints sizes by category and the largest extensions with deltas against the previous snapshot
+func PrintBreakdown(prevSnapshot, currSnapshot SnapshotStruct) {
+	categories := SumBreakdown(currSnapshot, byCategory)
+	if len(categories) == 0 {
+		return // no breakdown (eg. snapshot of an older version)
+	}
+	prevCategories := SumBreakdown(prevSnapshot, byCategory)
+	extensions := SumBreakdown(currSnapshot, byExtension)
+	prevExtensions := SumBreakdown(prevSnapshot, byExtension)
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetTitle("by category and extension")
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.AppendHeader(table.Row{"type", "size", "files", "share"})
+
+	var total int64
+	for _, stat := range categories {
+		total += stat.Size
+	}
+	appendRows := func(stats, prevStats map[string]UsageStat, keys []string, label func(string) string) {
+		for _, key := range keys {
+			stat := stats[key]
+			var deltaSize, deltaFiles string
+			if len(prevStats) > 0 { // the previous snapshot has the breakdown
+				prev := prevStats[key]
+				if prev.Size != stat.Size {
+					deltaSize = " " + HumanSizeSign(stat.Size-prev.Size)
+				}
+				if prev.Files != stat.Files {
+					deltaFiles = fmt.Sprintf(" (%+d)", stat.Files-prev.Files)
+				}
+			}
+			share := ""
+			if total > 0 {
+				share = fmt.Sprintf("%.1f%%", float64(stat.Size)*100/float64(total))
+			}
+			tableWriter.AppendRow(table.Row{
+				label(key),
+				HumanSize(stat.Size) + color.HiMagentaString(deltaSize),
+				fmt.Sprint(stat.Files) + deltaFiles,
+				share,
+			})
+		}
+	}
+
+	// categories which disappeared are shown with zero size to see what has been cleaned up
+	for key := range prevCategories {
+		if _, ok := categories[key]; !ok {
+			categories[key] = UsageStat{}
+		}
+	}
+	appendRows(categories, prevCategories, sortedKeys(categories), func(key string) string {
+		return color.HiBlueString(key)
+	})
+	tableWriter.AppendSeparator()
+	keys := sortedKeys(extensions)
+	if len(keys) > breakdownTopExtensions {
+		keys = keys[:breakdownTopExtensions]
+	}
+	appendRows(extensions, prevExtensions, keys, func(key string) string {
+		if key == category.NoExtension || key == category.OtherExtensions {
+			return key
+		}
+		return "." + key
+	})
+	tableWriter.Render()
+}
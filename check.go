@@ -0,0 +1,89 @@
+package main
+
+import (
+	"space-monitor/libs/alerting"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"time"
+)
+
+// loadSnapshotDirs loads info of all configured directories into the snapshot without the file maps (missing ones are left empty)
+func loadSnapshotDirs(snapshot *SnapshotStruct) {
+	for _, dir := range gCfg.Dirs {
+		info, err := gStore.LoadDirInfo(*snapshot, AbsPath(dir.Path), false) // sizes only, the file maps aren't compared
+		if err != nil {
+			gLogger.Println(err)
+		}
+		snapshot.InfoList = append(snapshot.InfoList, info)
+	}
+}
+
+// CheckRules returns alert rules of the config with free space rules of -w and -c flags added
+func CheckRules() ([]Config_Alert, error) {
+	rules := append([]Config_Alert{}, gCfg.Alerts...)
+	if *gWarning != "" {
+		rules = append(rules, Config_Alert{Name: "free space", Level: "warning", FreeBelow: *gWarning})
+	}
+	if *gCritical != "" {
+		rules = append(rules, Config_Alert{Name: "free space", Level: "critical", FreeBelow: *gCritical})
+	}
+	for _, rule := range rules {
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// CommandCheck runs as Nagios/Icinga plugin: prints single status line with perfdata and returns the plugin exit code
+func CommandCheck() int {
+	unknown := func(err error) int {
+		gLogger.Println("check:", err)
+		fmt2.Println("SPACE-MONITOR UNKNOWN -", err)
+		return ExitFatal
+	}
+	rules, err := CheckRules()
+	if err != nil {
+		return unknown(err)
+	}
+
+	// use the latest snapshot if it is fresh enough, scan otherwise
+	var prev, curr SnapshotStruct
+	latest, err := LoadPrevSnapshot(0)
+	fresh := false
+	if err == nil && *gFresh != "" {
+		maxAge, err := human.ParseDuration(*gFresh)
+		if err != nil {
+			return unknown(err)
+		}
+		fresh = time.Since(latest.StartTime) <= maxAge
+	}
+	if fresh {
+		curr = latest
+		prev, _ = LoadPrevSnapshot(1)
+	} else {
+		prev = latest
+	}
+	if prev.ID != "" {
+		loadSnapshotDirs(&prev)
+	}
+	if !fresh {
+		stopProgress := gProgress.Report()
+		curr, err = ScanSnapshot(prev, time.Now(), nil, nil)
+		stopProgress()
+		if err != nil {
+			return unknown(err)
+		}
+	}
+	if curr.InfoList == nil {
+		loadSnapshotDirs(&curr)
+	}
+
+	alerts := EvaluateAlerts(rules, prev, curr, NewTrends(curr))
+	level := MaxAlertLevel(alerts)
+	fmt2.Println(alerting.CheckOutput(level, alerts, rules, curr, AbsPath))
+	if !fresh && !*gNoSave {
+		DeleteOldSnapshots()
+	}
+	return int(level)
+}
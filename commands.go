@@ -0,0 +1,161 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
+	"strings"
+	"time"
+)
+
+// ParseCommand returns command name (first non-flag argument) and its arguments.
+// Flags are allowed before and after the command (eg. "space-monitor -config my.yaml list -nosave")
+func ParseCommand() (string, []string) {
+	var args []string
+	rest := flag.Args()
+	for len(rest) > 0 {
+		args = append(args, rest[0])
+		_ = flag.CommandLine.Parse(rest[1:]) // exits on error
+		rest = flag.Args()
+	}
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], args[1:]
+}
+
+// RunCommand executes the command and returns process exit code
+func RunCommand(command string, args []string) int {
+	switch command {
+	case "list":
+		return CommandList()
+	case "tag":
+		return CommandTag(args, true)
+	case "untag":
+		return CommandTag(args, false)
+	case "prune":
+		return CommandPrune(*gDryRun)
+	case "migrate":
+		return CommandMigrate(*gDryRun)
+	case "history":
+		return CommandHistory(args)
+	case "find":
+		return CommandFind(args)
+	case "forecast":
+		return CommandForecast()
+	case "check":
+		return CommandCheck()
+	case "treemap":
+		return CommandTreemap(args)
+	case "explore":
+		return CommandExplore(args)
+	case "watch":
+		return CommandWatch()
+	case "export":
+		return CommandExport(args)
+	case "import":
+		return CommandImport(args, *gDryRun)
+	case "folded":
+		return CommandFolded(args)
+	case "stale":
+		return CommandStale(args)
+	}
+	LogErr("unknown command:", command)
+	fmt2.Println("commands:")
+	fmt2.Println("  list [-since 7d]                 list snapshots")
+	fmt2.Println("  tag <snapshot> <tag>             tag (pin) the snapshot")
+	fmt2.Println("  untag <snapshot> <tag>           remove the tag from the snapshot")
+	fmt2.Println("  prune [-dry-run]                 delete snapshots according to the retention policy")
+	fmt2.Println("  migrate [-dry-run] [-to bolt]    upgrade data dir to the current snapshot format (with backup)")
+	fmt2.Println("                                   or copy all snapshots to another storage")
+	fmt2.Println("  history <path> [-since 30d]      size and file count history of the path")
+	fmt2.Println("  find <glob> [-regex] [-min-size 1G] [-max-size 10G]")
+	fmt2.Println("                                   search paths across all snapshots (detailed mode)")
+	fmt2.Println("  forecast                         growth rates and time to full of directories and mounts")
+	fmt2.Println("  check [-w 20%] [-c 10%] [-fresh 1h]")
+	fmt2.Println("                                   Nagios/Icinga plugin: status line with perfdata, exit code 0-3")
+	fmt2.Println("  treemap <path> [snapshot] [-against prev] [-depth 3] [-min-size 1M] [-o treemap.svg]")
+	fmt2.Println("                                   SVG treemap of the directory colored by growth (detailed mode)")
+	fmt2.Println("  explore [snapshot] [-against prev]")
+	fmt2.Println("                                   browse stored snapshots interactively like ncdu (detailed mode)")
+	fmt2.Println("  watch [-interval 10m]            rescan periodically and redraw the summary table in place")
+	fmt2.Println("  export <path> [snapshot|now] [-o file.json]")
+	fmt2.Println("                                   export the directory tree in ncdu JSON format (ncdu -f file.json)")
+	fmt2.Println("  import <file>... [-root /dir] [-time 2021-03-01] [-pin] [-dry-run]")
+	fmt2.Println("                                   import 'du -ab' outputs and ncdu JSON exports as snapshots")
+	fmt2.Println("  folded <path> [snapshot|now] [-against prev] [-o stacks.folded]")
+	fmt2.Println("                                   folded stacks of file sizes (or growth) for flamegraph.pl and speedscope")
+	fmt2.Println("  stale [snapshot]                 file age histograms and the largest subtrees not modified for stale-age")
+	return 1
+}
+
+// SinceTime returns start of the time range set by the -since flag (zero time if not set)
+func SinceTime() (time.Time, error) {
+	if *gSince == "" {
+		return time.Time{}, nil
+	}
+	since, err := human.ParseDuration(*gSince)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-since), nil
+}
+
+// CommandList prints the list of snapshots with their tags
+func CommandList() int {
+	tableWriter := table.NewWriter()
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.AppendHeader(table.Row{"snapshot", "age", "free space", "tags"})
+	from, err := SinceTime()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	list, err := gStore.ListRange(from, time.Now())
+	var skipped *store.SkippedError
+	if errors.As(err, &skipped) {
+		LogErr(err)
+	} else if err != nil {
+		LogErr(err)
+		return 1
+	}
+	for _, snap := range list {
+		tableWriter.AppendRow(table.Row{
+			color.HiBlueString(snap.ID),
+			TimeAgo(snap.StartTime),
+			HumanSize(snap.FreeSpace),
+			ColorPale(strings.Join(snap.Tags, ",")),
+		})
+	}
+	tableWriter.Render()
+	return 0
+}
+
+// CommandTag adds (or removes) the tag to the snapshot. Tagged snapshots are pinned and survive rotation
+func CommandTag(args []string, add bool) int {
+	if len(args) != 2 {
+		LogErr("usage: tag|untag <snapshot> <tag>")
+		return 1
+	}
+	snap, err := FindSnapshot(args[0])
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	if add {
+		snap.AddTag(args[1])
+	} else {
+		snap.RemoveTag(args[1])
+	}
+	if err := gStore.Save(snap); err != nil {
+		LogErr(err)
+		return 1
+	}
+	fmt2.Println(snap.ID, "tags:", strings.Join(snap.Tags, ","))
+	return 0
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"github.com/acarl005/stripansi"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"html"
+	"os"
+	"space-monitor/libs/ansihtml"
+	"space-monitor/libs/mail"
+	"strings"
+	"time"
+)
+
+// Email notifier modes
+const (
+	EmailEveryRun    = "every-run"
+	EmailAlert       = "alert"        // only runs with triggered alerts
+	EmailDailyDigest = "daily-digest" // summary of the snapshots of the last day
+)
+
+// Config_Email email notifier settings
+type Config_Email struct {
+	Host     string   `yaml:"host"` // SMTP server. Email notifier is disabled if empty
+	Port     int      `yaml:"port"`
+	Security string   `yaml:"security"` // starttls (default), tls or none (no authentication, except to localhost)
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password" env:"SPACE_MONITOR_SMTP_PASSWORD"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	Mode     string   `yaml:"mode"` // every-run, alert (default) or daily-digest
+}
+
+// Enabled returns true if the email notifier is configured
+func (e Config_Email) Enabled() bool {
+	return e.Host != ""
+}
+
+// Validate checks the email settings
+func (e Config_Email) Validate() error {
+	if !e.Enabled() {
+		return nil
+	}
+	if e.From == "" || len(e.To) == 0 {
+		return errors.New("email: from and to addresses are required")
+	}
+	if e.Security != "starttls" && e.Security != "tls" && e.Security != "none" {
+		return fmt.Errorf("email: unknown security %q (starttls, tls or none expected)", e.Security)
+	}
+	if e.Mode != EmailEveryRun && e.Mode != EmailAlert && e.Mode != EmailDailyDigest {
+		return fmt.Errorf("email: unknown mode %q (%s, %s or %s expected)", e.Mode, EmailEveryRun, EmailAlert, EmailDailyDigest)
+	}
+	if err := e.Server().Validate(); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+// Server returns SMTP server settings
+func (e Config_Email) Server() mail.Server {
+	return mail.Server{Host: e.Host, Port: e.Port, Username: e.Username, Password: e.Password, Security: e.Security}
+}
+
+// ReportHTML returns HTML document of the ANSI-colored report
+func ReportHTML(title string, report string) string {
+	return `<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>` + html.EscapeString(title) + `</title></head>
+<body style="margin:0;background:#1e1e1e">
+<pre style="margin:0;padding:12px;background:#1e1e1e;color:#cccccc;font-family:Menlo,Consolas,'DejaVu Sans Mono',monospace;font-size:13px;line-height:1.2">` +
+		ansihtml.Convert(report) + `</pre>
+</body></html>`
+}
+
+// SendEmail sends the report as HTML with the plain text fallback part
+func SendEmail(subject string, report string) error {
+	cfg := gCfg.Notify.Email
+	message := mail.Message{
+		From:    cfg.From,
+		To:      cfg.To,
+		Subject: subject,
+		Text:    stripansi.Strip(report),
+		HTML:    ReportHTML(subject, report),
+	}
+	start := time.Now()
+	if err := cfg.Server().Send(message); err != nil {
+		return fmt.Errorf("email to %s: %w", strings.Join(cfg.To, ", "), err)
+	}
+	gLogger.Println("email sent to", strings.Join(cfg.To, ", "), "in", time.Since(start).Round(time.Millisecond))
+	return nil
+}
+
+// EmailSubject returns subject of the run report email
+func EmailSubject(curr SnapshotStruct, level AlertLevel) string {
+	return fmt.Sprintf("[space-monitor] %s: %s, %s free", ReportTitle(), level, HumanSize(curr.FreeSpace))
+}
+
+// DigestReport returns summary table of the snapshots started after the time
+func DigestReport(from time.Time) (string, int) {
+	list, err := ListSnapshots()
+	if err != nil {
+		gLogger.Println(err)
+	}
+	tableWriter := table.NewWriter()
+	tableWriter.SetStyle(table.StyleRounded)
+	header := table.Row{"snapshot", "free space"}
+	for _, dir := range gCfg.Dirs {
+		header = append(header, shorifyPath(AbsPath(dir.Path)))
+	}
+	tableWriter.AppendHeader(header)
+	count := 0
+	for _, snap := range list {
+		if snap.StartTime.Before(from) {
+			continue
+		}
+		count++
+		row := table.Row{snap.StartTime.Format("02 Jan 15:04"), HumanSize(snap.FreeSpace)}
+		for _, dir := range gCfg.Dirs {
+			if info, err := LoadDirInfo(snap, dir); err == nil {
+				row = append(row, HumanSize(info.Size))
+			} else {
+				row = append(row, "-")
+			}
+		}
+		tableWriter.AppendRow(row)
+	}
+	return tableWriter.Render(), count
+}
+
+// digestDue returns true if the daily digest has to be sent. The time of the last digest is kept in the data dir
+func digestDue(now time.Time) (bool, time.Time) {
+	file := gDataDir + "/email-digest-time"
+	bytes, err := os.ReadFile(file)
+	last, parseErr := time.Parse(time.RFC3339, strings.TrimSpace(string(bytes)))
+	if err != nil || parseErr != nil {
+		_ = os.WriteFile(file, []byte(now.Format(time.RFC3339)), 0666) // start collecting
+		return false, last
+	}
+	if now.Sub(last) < 24*time.Hour {
+		return false, last
+	}
+	if err := os.WriteFile(file, []byte(now.Format(time.RFC3339)), 0666); err != nil {
+		LogErr(err)
+	}
+	return true, last
+}
+
+// NotifyEmail sends the run report according to the email mode. Skip is set when the alert state is not changed (on-state-change option)
+func NotifyEmail(curr SnapshotStruct, alerts []Alert, skip bool) {
+	cfg := gCfg.Notify.Email
+	level := MaxAlertLevel(alerts)
+	var subject, report string
+	switch cfg.Mode {
+	case EmailEveryRun, EmailAlert:
+		if skip || (cfg.Mode == EmailAlert && level == AlertOK) {
+			return
+		}
+		subject, report = EmailSubject(curr, level), gReport.String()
+	case EmailDailyDigest:
+		due, last := digestDue(curr.StartTime)
+		if !due {
+			return
+		}
+		digest, count := DigestReport(last)
+		subject = fmt.Sprintf("%s (daily digest, %d snapshots)", EmailSubject(curr, level), count)
+		report = digest + "\n\nlatest run:\n" + gReport.String()
+	}
+	if err := SendEmail(subject, report); err != nil {
+		LogErr(err)
+	}
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"space-monitor/libs/diff"
+	"space-monitor/libs/term"
+	"strings"
+)
+
+// ExploreEntry is a row of the explorer: a child of the current directory in the selected or the comparison snapshot
+type ExploreEntry = diff.Entry
+
+// exploreDir is a loaded directory of the snapshot with the children index of its file map
+type exploreDir struct {
+	info     DirInfoStruct
+	children map[string][]string
+}
+
+// Explorer is the state of the interactive snapshot explorer
+type Explorer struct {
+	snapshots   []SnapshotStruct
+	current     int // index of the browsed snapshot
+	against     int // index of the comparison snapshot (-1 if none)
+	pinAgainst  bool
+	path        string // browsed directory ("" is the list of configured directories)
+	cursor      int
+	offset      int
+	sortByDelta bool
+	message     string
+	cache       map[string]exploreDir
+}
+
+// NewExplorer creates the explorer of the snapshot list
+func NewExplorer(snapshots []SnapshotStruct, current, against int) *Explorer {
+	return &Explorer{
+		snapshots:  snapshots,
+		current:    current,
+		against:    against,
+		pinAgainst: against >= 0 && against != current-1,
+		cache:      map[string]exploreDir{},
+	}
+}
+
+// loadDir loads the configured directory of the snapshot (cached)
+func (e *Explorer) loadDir(index int, dir Config_DirectorySettings) (exploreDir, bool) {
+	if index < 0 || index >= len(e.snapshots) {
+		return exploreDir{}, false
+	}
+	snapshot := e.snapshots[index]
+	key := snapshot.ID + "\x00" + dir.Path
+	if loaded, ok := e.cache[key]; ok {
+		return loaded, loaded.info.Path != ""
+	}
+	info, err := LoadDirInfo(snapshot, dir)
+	if err != nil {
+		gLogger.Println("explore:", err)
+	}
+	loaded := exploreDir{info: info, children: diff.Index(info.FileMap, info.Path)}
+	e.cache[key] = loaded
+	return loaded, info.Path != ""
+}
+
+// Entries returns children of the current directory sorted by size (or by delta)
+func (e *Explorer) Entries() []ExploreEntry {
+	var entries []ExploreEntry
+	if e.path == "" {
+		for _, dir := range gCfg.Dirs {
+			curr, ok := e.loadDir(e.current, dir)
+			prev, prevOk := e.loadDir(e.against, dir)
+			entry := ExploreEntry{Path: AbsPath(dir.Path), IsDir: true, Size: curr.info.Size, Deleted: !ok}
+			if prevOk {
+				entry.Delta = curr.info.Size - prev.info.Size
+			} else {
+				entry.Added = ok && e.against >= 0
+			}
+			entries = append(entries, entry)
+		}
+	} else {
+		dir, _ := FindDirSettings(e.path)
+		curr, _ := e.loadDir(e.current, dir)
+		prev, prevOk := e.loadDir(e.against, dir)
+		entries = diff.Children(curr.info.FileMap, prev.info.FileMap, curr.children, prev.children, e.path, prevOk)
+	}
+	diff.SortEntries(entries, e.sortByDelta)
+	return entries
+}
+
+// Enter opens the directory of the entry
+func (e *Explorer) Enter(entry ExploreEntry) {
+	if !entry.IsDir || entry.Deleted {
+		return
+	}
+	dir, _ := FindDirSettings(entry.Path)
+	if !dir.IsDetailed() {
+		e.message = "no file map: " + shorifyPath(dir.Path) + " is not scanned in detailed mode"
+		return
+	}
+	e.path, e.cursor, e.offset = entry.Path, 0, 0
+}
+
+// Leave goes to the parent directory keeping the cursor on the left one
+func (e *Explorer) Leave() {
+	if e.path == "" {
+		return
+	}
+	left := e.path
+	if dir, _ := FindDirSettings(e.path); e.path == AbsPath(dir.Path) {
+		e.path = ""
+	} else {
+		e.path = filepath.Dir(e.path)
+	}
+	e.cursor, e.offset = 0, 0
+	for i, entry := range e.Entries() {
+		if entry.Path == left {
+			e.cursor = i
+		}
+	}
+}
+
+// SelectSnapshot switches to the snapshot by index. The comparison snapshot follows it unless set with -against.
+// The browsed directory is kept if it exists in the snapshot, otherwise the nearest existing parent is opened
+func (e *Explorer) SelectSnapshot(index int) {
+	if index < 0 || index >= len(e.snapshots) {
+		return
+	}
+	e.current = index
+	if !e.pinAgainst {
+		e.against = index - 1
+	}
+	for e.path != "" {
+		dir, _ := FindDirSettings(e.path)
+		if curr, ok := e.loadDir(e.current, dir); ok {
+			if _, ok := curr.info.FileMap[e.path]; ok {
+				break
+			}
+		}
+		e.Leave()
+	}
+}
+
+// Render returns the screen lines for the terminal size
+func (e *Explorer) Render(width, height int) []string {
+	snapshot := e.snapshots[e.current]
+	header := fmt.Sprintf(" %s  snapshot %d/%d %s", ReportTitle(), e.current+1, len(e.snapshots), snapshot.StartTime.Format("02 Jan 2006 15:04:05"))
+	if len(snapshot.Tags) > 0 {
+		header += " [" + strings.Join(snapshot.Tags, ", ") + "]"
+	}
+	if e.against >= 0 {
+		header += fmt.Sprintf("  vs %s (%s)", e.snapshots[e.against].StartTime.Format("02 Jan 15:04"), TimeAgo(e.snapshots[e.against].StartTime))
+	}
+	location := " --- " + shorifyPath(e.path) + " "
+	if e.path == "" {
+		location = " --- configured directories "
+	}
+	lines := []string{
+		color.New(color.Bold, color.FgHiYellow).Sprint(fitString(header, width)),
+		ColorPale("%s", fitString(location+strings.Repeat("-", width), width)),
+	}
+
+	entries := e.Entries()
+	rows := height - 4
+	if rows < 1 {
+		rows = 1
+	}
+	if e.cursor >= len(entries) {
+		e.cursor = len(entries) - 1
+	}
+	if e.cursor < 0 {
+		e.cursor = 0
+	}
+	if e.cursor < e.offset {
+		e.offset = e.cursor
+	}
+	if e.cursor >= e.offset+rows {
+		e.offset = e.cursor - rows + 1
+	}
+	var maxSize int64
+	for _, entry := range entries {
+		if entry.Size > maxSize {
+			maxSize = entry.Size
+		}
+	}
+	for i := e.offset; i < len(entries) && i < e.offset+rows; i++ {
+		lines = append(lines, e.renderEntry(entries[i], maxSize, width, i == e.cursor))
+	}
+	for len(lines) < height-2 {
+		lines = append(lines, "")
+	}
+
+	var total int64
+	for _, entry := range entries {
+		total += entry.Size
+	}
+	status := fmt.Sprintf(" total %s, %d items", HumanSize(total), len(entries))
+	if e.message != "" {
+		status = " " + e.message
+		e.message = ""
+	}
+	sortName := "size"
+	if e.sortByDelta {
+		sortName = "delta"
+	}
+	help := fmt.Sprintf(" ↑↓ move  →/enter open  ← up  [ ] older/newer snapshot  s sort (%s)  q quit", sortName)
+	lines = append(lines, fitString(status, width), ColorPale("%s", fitString(help, width)))
+	return lines
+}
+
+// renderEntry returns the explorer row of the entry
+func (e *Explorer) renderEntry(entry ExploreEntry, maxSize int64, width int, selected bool) string {
+	const barWidth = 12
+	bar := ""
+	if maxSize > 0 {
+		filled := int(entry.Size * barWidth / maxSize)
+		bar = strings.Repeat("#", filled) + strings.Repeat(" ", barWidth-filled)
+	} else {
+		bar = strings.Repeat(" ", barWidth)
+	}
+	delta := ""
+	switch {
+	case entry.Deleted:
+		delta = "deleted"
+	case entry.Added:
+		delta = "new"
+	case entry.Delta != 0:
+		delta = HumanSizeSign(entry.Delta)
+	}
+	name := filepath.Base(entry.Path)
+	if e.path == "" {
+		name = shorifyPath(entry.Path)
+	}
+	if entry.IsDir {
+		name += "/"
+	}
+	line := fmt.Sprintf(" %10s %10s [%s] %s", HumanSize(entry.Size), delta, bar, name)
+	line = fitString(line, width)
+	switch {
+	case selected:
+		return color.New(color.ReverseVideo).Sprint(line + strings.Repeat(" ", width-len([]rune(line))))
+	case entry.Deleted:
+		return color.RedString("%s", line)
+	case entry.Added:
+		return color.GreenString("%s", line)
+	case entry.Delta != 0:
+		return color.HiMagentaString("%s", line)
+	}
+	return line
+}
+
+// fitString cuts the string to the width
+func fitString(s string, width int) string {
+	runes := []rune(s)
+	if width < 0 {
+		width = 0
+	}
+	if len(runes) > width {
+		return string(runes[:width])
+	}
+	return s
+}
+
+// CommandExplore browses stored snapshots interactively (like ncdu) without rescanning
+func CommandExplore(args []string) int {
+	if len(args) > 1 {
+		LogErr("usage: explore [snapshot] [-against snapshot]")
+		return 1
+	}
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	if len(snapshots) == 0 {
+		LogErr(ErrNoSnapshots)
+		return 1
+	}
+	ref := "last"
+	if len(args) == 1 {
+		ref = args[0]
+	}
+	current, err := snapshotIndex(snapshots, ref)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	against := current - 1
+	if *gAgainst != "" {
+		if against, err = snapshotIndex(snapshots, *gAgainst); err != nil {
+			LogErr(err)
+			return 1
+		}
+	}
+
+	restore, err := term.MakeRaw(int(os.Stdin.Fd()))
+	if err != nil {
+		LogErr("explore needs an interactive terminal:", err)
+		return 1
+	}
+	out := bufio.NewWriter(os.Stdout)
+	out.WriteString("\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer func() {
+		out.WriteString("\x1b[?25h\x1b[?1049l")
+		out.Flush()
+		restore()
+	}()
+
+	explorer := NewExplorer(snapshots, current, against)
+	in := bufio.NewReader(os.Stdin)
+	for {
+		width, height, err := term.Size(int(os.Stdout.Fd()))
+		if err != nil {
+			width, height = 80, 24
+		}
+		out.WriteString("\x1b[H\x1b[2J")
+		out.WriteString(strings.Join(explorer.Render(width, height), "\r\n"))
+		out.Flush()
+
+		key, err := term.ReadKey(in)
+		if err != nil {
+			return 0
+		}
+		entries := explorer.Entries()
+		page := height - 4
+		switch {
+		case key.Code == term.Up || key.Rune == 'k':
+			explorer.cursor--
+		case key.Code == term.Down || key.Rune == 'j':
+			explorer.cursor++
+		case key.Code == term.PageUp:
+			explorer.cursor -= page
+		case key.Code == term.PageDown:
+			explorer.cursor += page
+		case key.Code == term.Home || key.Rune == 'g':
+			explorer.cursor = 0
+		case key.Code == term.End || key.Rune == 'G':
+			explorer.cursor = len(entries) - 1
+		case key.Code == term.Right || key.Code == term.Enter || key.Rune == 'l':
+			if explorer.cursor >= 0 && explorer.cursor < len(entries) {
+				explorer.Enter(entries[explorer.cursor])
+			}
+		case key.Code == term.Left || key.Code == term.Backspace || key.Rune == 'h':
+			explorer.Leave()
+		case key.Rune == '[':
+			explorer.SelectSnapshot(explorer.current - 1)
+		case key.Rune == ']':
+			explorer.SelectSnapshot(explorer.current + 1)
+		case key.Rune == 's':
+			explorer.sortByDelta = !explorer.sortByDelta
+		case key.Rune == 'q' || key.Code == term.Escape || key.Rune == 3: // 3 is Ctrl+C
+			return 0
+		}
+	}
+}
+
+// snapshotIndex returns index of the snapshot found by reference (see FindSnapshot) in the list
+func snapshotIndex(snapshots []SnapshotStruct, ref string) (int, error) {
+	snapshot, err := FindSnapshot(ref)
+	if err != nil {
+		return -1, err
+	}
+	for i := range snapshots {
+		if snapshots[i].ID == snapshot.ID {
+			return i, nil
+		}
+	}
+	return -1, errors.New("snapshot " + ref + " not found")
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/ncdu"
+	"space-monitor/libs/store"
+	"time"
+)
+
+// NcduTree converts the file map subtree of the root path into ncdu tree
+func NcduTree(fileMap map[string]GobFileInfo, root string) *ncdu.Entry {
+	children := map[string][]string{}
+	for path := range fileMap {
+		if path != root && store.IsSubPath(path, root) {
+			parent := filepath.Dir(path)
+			children[parent] = append(children[parent], path)
+		}
+	}
+	var build func(path, name string) *ncdu.Entry
+	build = func(path, name string) *ncdu.Entry {
+		info := fileMap[path]
+		entry := &ncdu.Entry{Name: name, IsDir: info.IsDir}
+		if !info.IsDir {
+			entry.Size = info.Size // directory sizes of the file map are aggregated, ncdu sums them itself
+			return entry
+		}
+		sort.Strings(children[path])
+		for _, child := range children[path] {
+			entry.Children = append(entry.Children, build(child, filepath.Base(child)))
+		}
+		return entry
+	}
+	return build(root, root)
+}
+
+// LoadPathFileMap loads the directory info with the file map containing the path from the snapshot.
+// Ref 'now' scans the path instead (any path can be scanned, the file map is collected regardless of the detailed mode)
+func LoadPathFileMap(path, ref string) (DirInfoStruct, error) {
+	var info DirInfoStruct
+	var err error
+	if ref == "now" {
+		info, err = ProcessDirectory(Config_DirectorySettings{Path: path}, ScanOptions{StartTime: time.Now(), FileMap: true})
+	} else {
+		dir, ok := FindDirSettings(path)
+		if !ok {
+			return info, fmt.Errorf("%s is not inside any configured directory (use 'now' to scan it)", path)
+		}
+		var snapshot SnapshotStruct
+		if snapshot, err = FindSnapshot(ref); err != nil {
+			return info, err
+		}
+		if info, err = gStore.LoadDirInfo(snapshot, AbsPath(dir.Path), true); err == nil && info.FileMap == nil {
+			return info, fmt.Errorf("%s has no file map (detailed mode is off)", shorifyPath(dir.Path))
+		}
+	}
+	if err != nil {
+		return info, err
+	}
+	if _, ok := info.FileMap[path]; !ok {
+		return info, fmt.Errorf("%s is not found", path)
+	}
+	return info, nil
+}
+
+// OpenOutput returns the -o file (or stdout if not set) and the function closing it
+func OpenOutput() (io.Writer, func(), error) {
+	if *gOutput == "" {
+		return fmt2.OutWriter, func() {}, nil
+	}
+	file, err := os.Create(*gOutput)
+	if err != nil {
+		return nil, nil, err
+	}
+	// noinspection GoUnhandledErrorResult
+	return file, func() { file.Close() }, nil
+}
+
+// CommandExport writes the directory tree of the snapshot ('now' scans the path instead) in ncdu JSON format
+// to the -o file or stdout. The export can be opened with 'ncdu -f file.json'
+func CommandExport(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		LogErr("usage: export <path> [snapshot|now] [-o file.json]")
+		return 1
+	}
+	path, err := filepath.Abs(AbsPath(args[0]))
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	ref := "last"
+	if len(args) == 2 {
+		ref = args[1]
+	}
+	info, err := LoadPathFileMap(path, ref)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+
+	out, closeOutput, err := OpenOutput()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	defer closeOutput()
+	if err := ncdu.Write(out, NcduTree(info.FileMap, path), "space-monitor", "1", info.StartTime); err != nil {
+		LogErr(err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"space-monitor/libs/find"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"space-monitor/libs/sparkline"
+)
+
+// FindMatch is a path found in the snapshot file maps
+type FindMatch = find.Match
+
+// FindInSnapshots scans detailed file maps of all snapshots for the paths accepted by the matcher.
+// A path is reported if its size was within [minSize, maxSize] in at least one snapshot (maxSize <= 0 means no upper bound)
+func FindInSnapshots(snapshots []SnapshotStruct, match func(string) bool, minSize, maxSize int64) []*FindMatch {
+	finder := find.NewFinder(match)
+	for i, snap := range snapshots {
+		for _, dir := range gCfg.Dirs {
+			if !dir.IsDetailed() {
+				continue
+			}
+			info, err := gStore.LoadDirInfo(snap, AbsPath(dir.Path), true)
+			if err != nil {
+				gLogger.Println("find:", err)
+				continue
+			}
+			finder.Add(i, info.FileMap)
+		}
+	}
+	return finder.Result(minSize, maxSize)
+}
+
+// CommandFind searches paths by glob or regex across all stored snapshots
+func CommandFind(args []string) int {
+	if len(args) != 1 {
+		LogErr("usage: find <glob> [-regex] [-min-size 1G] [-max-size 10G] [-since 30d]")
+		return 1
+	}
+	match, err := find.PathMatcher(args[0], *gRegex)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	var minSize, maxSize int64
+	if *gMinSize != "" {
+		if minSize, err = human.ParseSize(*gMinSize); err != nil {
+			LogErr(err)
+			return 1
+		}
+	}
+	if *gMaxSize != "" {
+		if maxSize, err = human.ParseSize(*gMaxSize); err != nil {
+			LogErr(err)
+			return 1
+		}
+	}
+	from, err := SinceTime()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	var snapshots []SnapshotStruct
+	all, err := ListSnapshots()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	for _, snap := range all {
+		if !snap.StartTime.Before(from) {
+			snapshots = append(snapshots, snap)
+		}
+	}
+
+	matches := FindInSnapshots(snapshots, match, minSize, maxSize)
+	if len(matches) == 0 {
+		fmt2.Println("nothing found in", len(snapshots), "snapshots")
+		return 0
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.AppendHeader(table.Row{"path", "first seen", "last seen", "size", "size over time"})
+	timeFormat := "02 Jan 2006 15:04"
+	for _, m := range matches {
+		path := shorifyPath(m.Path)
+		if m.IsDir {
+			path += "/"
+		}
+		lastSeen := snapshots[m.Last].StartTime.Format(timeFormat)
+		if m.Last == len(snapshots)-1 {
+			lastSeen = ColorPale("present")
+		}
+		var sizes []int64
+		for i := m.First; i <= m.Last; i++ {
+			sizes = append(sizes, m.Sizes[i]) // zero if the path was absent
+		}
+		tableWriter.AppendRow(table.Row{
+			color.HiBlueString(path),
+			snapshots[m.First].StartTime.Format(timeFormat),
+			lastSeen,
+			HumanSize(m.Sizes[m.Last]),
+			sparkline.Render(sizes),
+		})
+	}
+	tableWriter.AppendFooter(table.Row{fmt.Sprintf("%d found", len(matches)), "", "", "", fmt.Sprintf("%d snapshots", len(snapshots))})
+	tableWriter.Render()
+	return 0
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bufio"
+	"path/filepath"
+	"space-monitor/libs/folded"
+)
+
+// CommandFolded writes folded stacks of the path for flamegraph.pl or speedscope to the -o file or stdout.
+// With -against the weights are the growth since the comparison snapshot
+func CommandFolded(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		LogErr("usage: folded <path> [snapshot|now] [-against snapshot] [-o stacks.folded]")
+		return 1
+	}
+	path, err := filepath.Abs(AbsPath(args[0]))
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	ref := "last"
+	if len(args) == 2 {
+		ref = args[1]
+	}
+	info, err := LoadPathFileMap(path, ref)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	var prevMap map[string]GobFileInfo
+	if *gAgainst != "" {
+		prevInfo, err := LoadPathFileMap(path, *gAgainst)
+		if err != nil {
+			LogErr("comparison snapshot:", err)
+			return 1
+		}
+		prevMap = prevInfo.FileMap
+	}
+
+	out, closeOutput, err := OpenOutput()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	defer closeOutput()
+	writer := bufio.NewWriter(out)
+	for _, line := range folded.Stacks(info.FileMap, prevMap, path) {
+		writer.WriteString(line + "\n")
+	}
+	if err := writer.Flush(); err != nil {
+		LogErr(err)
+		return 1
+	}
+	return 0
+}
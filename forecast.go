@@ -0,0 +1,260 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"space-monitor/libs/trend"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// Config_Forecast growth trend settings
+type Config_Forecast struct {
+	Window    string  `yaml:"window"`     // time window of the snapshot series (eg. 30d)
+	Method    string  `yaml:"method"`     // linear (regression) or ewma
+	EWMAAlpha float64 `yaml:"ewma-alpha"` // weight of the latest rate for ewma method (0..1]
+}
+
+// Validate checks the forecast settings
+func (f Config_Forecast) Validate() error {
+	if _, err := human.ParseDuration(f.Window); err != nil {
+		return fmt.Errorf("forecast window: %w", err)
+	}
+	if f.Method != "linear" && f.Method != "ewma" {
+		return fmt.Errorf("unknown forecast method: %q (linear or ewma expected)", f.Method)
+	}
+	if f.EWMAAlpha <= 0 || f.EWMAAlpha > 1 {
+		return fmt.Errorf("forecast ewma-alpha must be in (0, 1] range")
+	}
+	return nil
+}
+
+// WindowStart returns the earliest time of the series ending at the time
+func (f Config_Forecast) WindowStart(end time.Time) time.Time {
+	window, _ := human.ParseDuration(f.Window) // validated in InitConfig
+	return end.Add(-window)
+}
+
+// Rate returns growth rate (per second) of the series by the configured method
+func (f Config_Forecast) Rate(points []trend.Point) (float64, bool) {
+	if f.Method == "ewma" {
+		return trend.EWMA(points, f.EWMAAlpha)
+	}
+	return trend.Linear(points)
+}
+
+// Growth is a growth estimation of a directory or mount
+type Growth struct {
+	Samples  int
+	Rate     float64       // bytes per second by the configured method
+	Linear   float64       // bytes per second by linear regression
+	EWMA     float64       // bytes per second by EWMA
+	Valid    bool          // false if there are not enough samples
+	HasFree  bool          // false if free space of the mount is unknown
+	Full     bool          // no free space left already
+	FullIn   time.Duration // time until the mount is full
+	WillFill bool          // false if the free space doesn't decrease
+}
+
+// newGrowth estimates growth of the series. Negative free space means unknown
+func newGrowth(points []trend.Point, free int64) Growth {
+	g := Growth{Samples: len(points), HasFree: free >= 0, Full: free == 0}
+	g.Rate, g.Valid = gCfg.Forecast.Rate(points)
+	g.Linear, _ = trend.Linear(points)
+	g.EWMA, _ = trend.EWMA(points, gCfg.Forecast.EWMAAlpha)
+	if g.Valid && g.HasFree {
+		g.FullIn, g.WillFill = trend.TimeToZero(float64(free), -g.Rate)
+	}
+	return g
+}
+
+// PerDay returns formatted growth per day
+func (g Growth) PerDay(rate float64) string {
+	if !g.Valid {
+		return ColorPale("n/a")
+	}
+	return HumanSizeSign(int64(rate * day.Seconds()))
+}
+
+// FullInString returns formatted time until the mount is full
+func (g Growth) FullInString() string {
+	switch {
+	case !g.HasFree:
+		return ColorPale("n/a")
+	case g.Full:
+		return color.HiRedString("full")
+	case !g.Valid:
+		return ColorPale("n/a")
+	case !g.WillFill:
+		return ColorPale("never")
+	case g.FullIn < 7*day:
+		return color.HiRedString(HumanDuration(g.FullIn))
+	case g.FullIn < 30*day:
+		return color.HiYellowString(HumanDuration(g.FullIn))
+	}
+	return HumanDuration(g.FullIn)
+}
+
+// SnapshotSeries returns stored snapshots of the forecast window ending with the current snapshot
+func SnapshotSeries(current SnapshotStruct) []SnapshotStruct {
+	from := gCfg.Forecast.WindowStart(current.StartTime)
+	list, err := ListSnapshots()
+	if err != nil {
+		gLogger.Println(err)
+	}
+	var series []SnapshotStruct
+	for _, snap := range list {
+		if !snap.StartTime.Before(from) && snap.StartTime.Before(current.StartTime) {
+			series = append(series, snap)
+		}
+	}
+	return append(series, current)
+}
+
+// DirHistory returns sizes of the directory in the forecast window ending with the current info
+func DirHistory(info DirInfoStruct) []trend.Point {
+	from := gCfg.Forecast.WindowStart(info.StartTime)
+	history, err := gStore.History(info.Path)
+	if err != nil {
+		gLogger.Println(err) // skipped items are tolerated
+	}
+	var points []trend.Point
+	for _, h := range history {
+		if !h.StartTime.Before(from) && h.StartTime.Before(info.StartTime) {
+			points = append(points, trend.Point{Time: h.StartTime, Value: float64(h.Size)})
+		}
+	}
+	return append(points, trend.Point{Time: info.StartTime, Value: float64(info.Size)})
+}
+
+// DirGrowth estimates growth of the directory by its size history. The time to full is
+// estimated for the directory mount as if nothing else grew there
+func DirGrowth(current SnapshotStruct, info DirInfoStruct, points []trend.Point) Growth {
+	mount, ok := current.FindMount(info.Path)
+	if !ok {
+		mount.Free = -1 // unknown (eg. snapshots of older versions)
+	}
+	return newGrowth(points, mount.Free)
+}
+
+// MountGrowth estimates growth of the used space of the mount
+func MountGrowth(series []SnapshotStruct, mountPath string) Growth {
+	var points []trend.Point
+	var free int64
+	for _, snap := range series {
+		for _, mount := range snap.Mounts {
+			if mount.Path == mountPath {
+				points = append(points, trend.Point{Time: snap.StartTime, Value: float64(mount.Total - mount.Free)})
+				free = mount.Free
+			}
+		}
+	}
+	return newGrowth(points, free)
+}
+
+// FreeSpaceGrowth estimates growth of the used space by the free space of the snapshots
+func FreeSpaceGrowth(series []SnapshotStruct) Growth {
+	var points []trend.Point
+	for _, snap := range series {
+		if snap.FreeSpace > 0 {
+			points = append(points, trend.Point{Time: snap.StartTime, Value: float64(-snap.FreeSpace)})
+		}
+	}
+	return newGrowth(points, series[len(series)-1].FreeSpace)
+}
+
+// Trends are the growth estimations of the run. They are computed once and shared by the summary table,
+// alerts and reports
+type Trends struct {
+	Series    []SnapshotStruct         // stored snapshots of the forecast window ending with the current snapshot
+	Dirs      map[string]Growth        // by directory path
+	History   map[string][]trend.Point // sizes of the directories in the forecast window, by directory path
+	FreeSpace Growth                   // used space by the free space of the snapshots
+}
+
+// NewTrends estimates growth of the directories and the used space of the current snapshot
+func NewTrends(current SnapshotStruct) Trends {
+	trends := Trends{Series: SnapshotSeries(current), Dirs: map[string]Growth{}, History: map[string][]trend.Point{}}
+	for _, info := range current.InfoList {
+		if info.Path != "" {
+			trends.History[info.Path] = DirHistory(info)
+			trends.Dirs[info.Path] = DirGrowth(current, info, trends.History[info.Path])
+		}
+	}
+	trends.FreeSpace = FreeSpaceGrowth(trends.Series)
+	return trends
+}
+
+// CommandForecast prints growth rates and time to full of the directories and mounts of the latest snapshot
+func CommandForecast() int {
+	current, err := LoadPrevSnapshot(0)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	series := SnapshotSeries(current)
+	fmt2.Printf("forecast by %s method, window %s (%d snapshots since %s)\n",
+		gCfg.Forecast.Method, gCfg.Forecast.Window, len(series), series[0].StartTime.Format("02 Jan 2006 15:04"))
+
+	header := table.Row{"", "size", "samples", "linear/day", "ewma/day", "est. full in", "full at"}
+	fullAt := func(g Growth) string {
+		if !g.Valid || !g.HasFree || g.Full || !g.WillFill {
+			return ""
+		}
+		return current.StartTime.Add(g.FullIn).Format("02 Jan 2006")
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetTitle("directories")
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	header[0] = "path"
+	tableWriter.AppendHeader(header)
+	for _, dir := range gCfg.Dirs {
+		info, err := LoadDirInfo(current, dir)
+		if err != nil {
+			LogErr(err)
+			continue
+		}
+		g := DirGrowth(current, info, DirHistory(info))
+		tableWriter.AppendRow(table.Row{
+			color.HiBlueString(shorifyPath(info.Path)),
+			HumanSize(info.Size),
+			g.Samples,
+			g.PerDay(g.Linear),
+			g.PerDay(g.EWMA),
+			g.FullInString(),
+			fullAt(g),
+		})
+	}
+	tableWriter.Render()
+
+	if len(current.Mounts) == 0 {
+		fmt2.Println(ColorPale("no mount usage in the latest snapshot"))
+		return 0
+	}
+	tableWriter = table.NewWriter()
+	tableWriter.SetTitle("mounts")
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	header[0], header[1] = "mount", "used"
+	tableWriter.AppendHeader(header)
+	for _, mount := range current.Mounts {
+		g := MountGrowth(series, mount.Path)
+		tableWriter.AppendRow(table.Row{
+			color.HiBlueString(mount.Path),
+			fmt.Sprintf("%s of %s, %s free", HumanSize(mount.Total-mount.Free), HumanSize(mount.Total), color.HiGreenString(HumanSize(mount.Free))),
+			g.Samples,
+			g.PerDay(g.Linear),
+			g.PerDay(g.EWMA),
+			g.FullInString(),
+			fullAt(g),
+		})
+	}
+	tableWriter.Render()
+	return 0
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/store"
+	"time"
+)
+
+// CommandMigrate upgrades all snapshots of the data dir to the current format. The data dir is backed up first.
+// With -to it copies all snapshots to another storage instead
+func CommandMigrate(dryRun bool) int {
+	if *gMigrateTo != "" {
+		return CopyToStorage(*gMigrateTo, dryRun)
+	}
+	if _, ok := gStore.(*store.FolderStore); !ok {
+		LogErr("migrate is only applicable to the folder storage (use -to to copy snapshots to another storage)")
+		return 1
+	}
+	outdated, err := store.FindOutdated(gDataDir)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	for _, snapshot := range outdated {
+		fmt2.Printf("%s: format v%d -> v%d\n", color.HiBlueString(filepath.Base(snapshot.Dir)), snapshot.FormatVersion, store.CurrentFormatVersion)
+	}
+	if len(outdated) == 0 {
+		fmt2.Printf("all snapshots are up to date (format version %d)\n", store.CurrentFormatVersion)
+		return 0
+	}
+	if dryRun {
+		fmt2.Println(len(outdated), "snapshots would be migrated")
+		return 0
+	}
+
+	backupDir := gDataDir + "-backup-" + time.Now().Format("20060102-150405")
+	fmt2.Println("backing up", gDataDir, "to", backupDir)
+	if err := store.MigrateDataDir(gDataDir, backupDir, outdated, gCfg.Compression); err != nil {
+		LogErr(err)
+		LogErr("the data dir backup is kept in", backupDir)
+		return 1
+	}
+	fmt2.Println(len(outdated), "snapshots migrated; backup:", backupDir)
+	return 0
+}
+
+// CopyToStorage copies snapshots of the configured directories (with file maps and reports) from the configured storage
+// to the target one. The source is left intact, snapshots already present in the target are skipped
+func CopyToStorage(target string, dryRun bool) int {
+	if target == gCfg.Storage || (target == "folder" && gCfg.Storage == "") {
+		LogErr("the target storage is the configured one:", target)
+		return 1
+	}
+	cfg := gCfg
+	cfg.Storage = target
+	dst, err := OpenStore(cfg)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	// noinspection GoUnhandledErrorResult
+	defer dst.Close()
+
+	var paths []string
+	for _, dir := range gCfg.Dirs {
+		paths = append(paths, AbsPath(dir.Path))
+	}
+	if dryRun {
+		src, _ := ListSnapshots()
+		existing, _ := dst.List()
+		present := map[string]bool{}
+		for _, snap := range existing {
+			present[snap.ID] = true
+		}
+		count := 0
+		for _, snap := range src {
+			if !present[snap.ID] {
+				count++
+			}
+		}
+		fmt2.Printf("%d snapshots would be copied to %s storage\n", count, target)
+		return 0
+	}
+	copied, err := store.CopyStore(dst, gStore, paths)
+	if err != nil {
+		LogErr("copy to", target, "storage failed:", err)
+		return 1
+	}
+	for _, id := range copied {
+		srcDir, dstDir := gStore.ReportDir(SnapshotStruct{ID: id}), dst.ReportDir(SnapshotStruct{ID: id})
+		if srcDir == "" || dstDir == "" || srcDir == dstDir {
+			continue
+		}
+		reports, _ := filepath.Glob(srcDir + "/report*")
+		for _, report := range reports {
+			if err := copyFile(report, dstDir+"/"+filepath.Base(report)); err != nil {
+				LogErr(err)
+			}
+		}
+	}
+	fmt2.Printf("%d snapshots copied to %s storage; set 'storage: %s' in the config to use it\n", len(copied), target, target)
+	return 0
+}
+
+// copyFile copies the file, creating the target directory
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0777); err != nil {
+		return err
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0666)
+}
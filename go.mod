@@ -4,12 +4,16 @@ go 1.19
 
 require (
 	github.com/acarl005/stripansi v0.0.0-20180116102854-5a71ef0e047d
+	github.com/cespare/xxhash/v2 v2.2.0
 	github.com/fatih/color v1.13.0
 	github.com/ilyakaznacheev/cleanenv v1.3.0
 	github.com/jedib0t/go-pretty/v6 v6.3.6
+	github.com/klauspost/compress v1.16.7
+	github.com/mattn/go-isatty v0.0.14
 	github.com/shirou/gopsutil v3.21.11+incompatible
 	github.com/xeonx/timeago v1.0.0-rc5
-	golang.org/x/sys v0.0.0-20210630005230-0f9fa26af87c
+	go.etcd.io/bbolt v1.3.7
+	golang.org/x/sys v0.4.0
 	gopkg.in/natefinch/lumberjack.v2 v2.0.0
 	gopkg.in/yaml.v2 v2.4.0
 )
@@ -19,7 +23,6 @@ require (
 	github.com/go-ole/go-ole v1.2.6 // indirect
 	github.com/joho/godotenv v1.4.0 // indirect
 	github.com/mattn/go-colorable v0.1.9 // indirect
-	github.com/mattn/go-isatty v0.0.14 // indirect
 	github.com/mattn/go-runewidth v0.0.13 // indirect
 	github.com/rivo/uniseg v0.2.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.2 // indirect
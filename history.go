@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"path/filepath"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/history"
+	"space-monitor/libs/sparkline"
+	"space-monitor/libs/store"
+	"strconv"
+	"time"
+)
+
+// HistoryPoint is the state of a path in a single snapshot
+type HistoryPoint = history.Point
+
+// FindDirSettings returns settings of the configured directory containing the path (the deepest one)
+func FindDirSettings(path string) (Config_DirectorySettings, bool) {
+	var found Config_DirectorySettings
+	for _, dir := range gCfg.Dirs {
+		dirPath := AbsPath(dir.Path)
+		if store.IsSubPath(path, dirPath) && len(dirPath) > len(AbsPath(found.Path)) {
+			found = dir
+		}
+	}
+	return found, found.Path != ""
+}
+
+// PathHistory collects the time series of the path state from all snapshots started after 'from'.
+// Configured directories are looked up in directory aggregates, nested paths in the detailed file maps
+func PathHistory(path string, from time.Time) ([]HistoryPoint, error) {
+	dir, ok := FindDirSettings(path)
+	if !ok {
+		return nil, fmt.Errorf("%s is not inside any configured directory", path)
+	}
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	points, err := history.Path(gStore, snapshots, AbsPath(dir.Path), path, from, gLogger.Println)
+	if err != nil {
+		return nil, err
+	}
+	if points == nil && path != AbsPath(dir.Path) && !dir.IsDetailed() {
+		return nil, fmt.Errorf("%s is not a configured directory and detailed mode is off for %s", path, dir.Path)
+	}
+	return points, nil
+}
+
+// CommandHistory prints size and file count history of the path
+func CommandHistory(args []string) int {
+	if len(args) != 1 {
+		LogErr("usage: history <path> [-since 30d]")
+		return 1
+	}
+	path, err := filepath.Abs(AbsPath(args[0]))
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	from, err := SinceTime()
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	points, err := PathHistory(path, from)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	if len(points) == 0 {
+		fmt2.Println("no history for", color.HiBlueString(path))
+		return 0
+	}
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetTitle("history of %s", color.HiBlueString(shorifyPath(path)))
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.AppendHeader(table.Row{"snapshot", "size", "delta", "files", "delta"})
+
+	var sizes []int64
+	var prev *HistoryPoint
+	var events []string
+	for i := range points {
+		point := points[i]
+		var deltaSize, deltaFiles string
+		if prev != nil && prev.Present && point.Present {
+			if point.Size != prev.Size {
+				deltaSize = HumanSizeSign(point.Size - prev.Size)
+			}
+			if point.Files != prev.Files {
+				deltaFiles = fmt.Sprintf("%+d", point.Files-prev.Files)
+			}
+		}
+		switch {
+		case point.Present && (prev == nil || !prev.Present):
+			if prev == nil {
+				events = append(events, "first seen in "+point.Snapshot.ID)
+			} else {
+				events = append(events, "appeared in "+point.Snapshot.ID)
+			}
+		case !point.Present && prev != nil && prev.Present:
+			events = append(events, "disappeared in "+point.Snapshot.ID+" (last seen in "+prev.Snapshot.ID+")")
+		}
+
+		size, files := ColorPale("-"), ColorPale("-")
+		if point.Present {
+			size, files = HumanSize(point.Size), strconv.Itoa(point.Files)
+		}
+		tableWriter.AppendRow(table.Row{
+			point.Snapshot.StartTime.Format("02 Jan 2006 15:04"),
+			size,
+			color.HiMagentaString(deltaSize),
+			files,
+			deltaFiles,
+		})
+		sizes = append(sizes, point.Size)
+		prev = &points[i]
+	}
+	tableWriter.Render()
+
+	first, last := points[0], points[len(points)-1]
+	fmt2.Printf(" size: %s %s → %s (%s over %s)\n",
+		sparkline.Render(sizes),
+		HumanSize(first.Size),
+		HumanSize(last.Size),
+		color.HiMagentaString(HumanSizeSign(last.Size-first.Size)),
+		HumanDuration(last.Snapshot.StartTime.Sub(first.Snapshot.StartTime)),
+	)
+	for _, event := range events {
+		fmt2.Println(" " + event)
+	}
+	return 0
+}
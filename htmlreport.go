@@ -0,0 +1,267 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/acarl005/stripansi"
+	"html/template"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/store"
+	"space-monitor/libs/svgchart"
+	"strings"
+)
+
+const htmlStyle = `
+body { font-family: -apple-system, "Segoe UI", Roboto, sans-serif; margin: 24px; color: #222; }
+h1 { font-size: 22px; margin-bottom: 4px; }
+.meta { color: #666; margin-bottom: 16px; }
+table { border-collapse: collapse; margin: 8px 0 16px; }
+th, td { border: 1px solid #ddd; padding: 4px 10px; text-align: right; }
+th { background: #f4f4f4; }
+td:first-child, th:first-child { text-align: left; }
+.path { color: #2472c8; font-family: monospace; }
+.delta { color: #bc3fbc; }
+.added { color: #0f8a4f; } .modified { color: #2472c8; } .deleted { color: #cd3131; }
+.alert { padding: 6px 10px; margin: 4px 0; border-radius: 4px; }
+.WARNING { background: #fff6d6; } .CRITICAL { background: #ffdcdc; }
+details { margin: 2px 0 2px 16px; } summary { cursor: pointer; }
+section > details { margin-left: 0; }
+.tree div { margin-left: 16px; font-family: monospace; }
+.tree summary { font-family: monospace; }
+.charts svg { margin: 0 16px 16px 0; border: 1px solid #eee; }
+`
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}} - {{.Time}}</title><style>` + htmlStyle + `</style></head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">snapshot {{.Time}}{{range .Tags}} [{{.}}]{{end}} · free space <b>{{.FreeSpace}}</b>{{if .FreeDelta}} <span class="delta">{{.FreeDelta}}</span>{{end}}{{if .Index}} · <a href="{{.Index}}">all snapshots</a>{{end}}</div>
+{{if .Alerts}}<section><h2>Alerts</h2>{{range .Alerts}}<div class="alert {{.Level}}"><b>{{.Level}}</b> {{.Rule}}: {{.Message}}</div>{{end}}</section>{{end}}
+<section><h2>Directories</h2>
+<table><tr><th>path</th><th>size</th><th>delta</th><th>dirs</th><th>files</th><th>growth/day</th><th>est. full in</th></tr>
+{{range .Dirs}}<tr><td class="path">{{.Path}}</td><td>{{.Size}}</td><td class="delta">{{.Delta}}</td><td>{{.Dirs}}</td><td>{{.Files}}</td><td>{{.Growth}}</td><td>{{.FullIn}}</td></tr>
+{{end}}</table></section>
+{{if .Charts}}<section><details open><summary><h2 style="display:inline">History</h2></summary><div class="charts">{{range .Charts}}{{.}}{{end}}</div></details></section>{{end}}
+{{if .Trees}}<section><h2>Changes</h2>{{range .Trees}}<details class="tree" open><summary class="path">{{.Path}} ({{.Count}} changes)</summary>{{.Tree}}</details>{{end}}</section>{{end}}
+</body></html>
+`))
+
+var htmlIndexTemplate = template.Must(template.New("index").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Title}} - snapshots</title><style>` + htmlStyle + `</style></head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="meta">{{len .Snapshots}} snapshots</div>
+{{if .Charts}}<div class="charts">{{range .Charts}}{{.}}{{end}}</div>{{end}}
+<table><tr><th>snapshot</th><th>free space</th><th>tags</th></tr>
+{{range .Snapshots}}<tr><td>{{if .Link}}<a href="{{.Link}}">{{.Time}}</a>{{else}}{{.Time}}{{end}}</td><td>{{.FreeSpace}}</td><td>{{.Tags}}</td></tr>
+{{end}}</table>
+</body></html>
+`))
+
+// htmlTreeNode is a node of the changes tree
+type htmlTreeNode struct {
+	name     string
+	change   *Change
+	children map[string]*htmlTreeNode
+}
+
+// changesTree renders changes of the directory as nested collapsible lists
+func changesTree(root string, changes []Change) template.HTML {
+	tree := &htmlTreeNode{children: map[string]*htmlTreeNode{}}
+	for i := range changes {
+		if changes[i].Path == root {
+			continue // the directory itself is summarized in the tree header
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(changes[i].Path, root), string(filepath.Separator))
+		node := tree
+		for _, name := range strings.Split(rel, string(filepath.Separator)) {
+			child, ok := node.children[name]
+			if !ok {
+				child = &htmlTreeNode{name: name, children: map[string]*htmlTreeNode{}}
+				node.children[name] = child
+			}
+			node = child
+		}
+		node.change = &changes[i]
+	}
+	var sb strings.Builder
+	var render func(node *htmlTreeNode)
+	render = func(node *htmlTreeNode) {
+		var names []string
+		for name := range node.children {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			child := node.children[name]
+			label := template.HTMLEscapeString(name)
+			if child.change != nil {
+				class := changeTypeNames[child.change.Type]
+				label = fmt.Sprintf(`<span class="%s">%s %s</span>`, class, label, HumanSize(child.change.File.Size))
+				if child.change.Delta != 0 {
+					label += fmt.Sprintf(` <span class="delta">%s</span>`, HumanSizeSign(child.change.Delta))
+				}
+			}
+			if len(child.children) > 0 {
+				sb.WriteString("<details><summary>" + label + "/</summary>")
+				render(child)
+				sb.WriteString("</details>")
+			} else {
+				sb.WriteString("<div>" + label + "</div>")
+			}
+		}
+	}
+	render(tree)
+	return template.HTML(sb.String())
+}
+
+// sizeChart returns SVG chart of the sizes
+func sizeChart(title string, points []svgchart.Point, color string) template.HTML {
+	chart := svgchart.Chart{Title: title, Width: 480, Height: 160, Color: color, Format: func(value float64) string {
+		return HumanSize(int64(value))
+	}}
+	return template.HTML(chart.Render(points))
+}
+
+// HTMLReport returns standalone HTML report of the run
+func HTMLReport(prev, curr SnapshotStruct, alerts []Alert, trends Trends, indexLink string) ([]byte, error) {
+	type dirRow struct{ Path, Size, Delta, Dirs, Files, Growth, FullIn string }
+	type tree struct {
+		Path  string
+		Count int
+		Tree  template.HTML
+	}
+	data := struct {
+		Title, Time, FreeSpace, FreeDelta, Index string
+		Tags                                     []string
+		Alerts                                   []Alert
+		Dirs                                     []dirRow
+		Charts                                   []template.HTML
+		Trees                                    []tree
+	}{
+		Title:     ReportTitle(),
+		Time:      curr.StartTime.Format("02 Jan 2006 15:04:05"),
+		FreeSpace: HumanSize(curr.FreeSpace),
+		Index:     indexLink,
+		Tags:      curr.Tags,
+		Alerts:    alerts,
+	}
+	if prev.FreeSpace > 0 && prev.FreeSpace != curr.FreeSpace {
+		data.FreeDelta = HumanSizeSign(curr.FreeSpace - prev.FreeSpace)
+	}
+
+	var freePoints []svgchart.Point
+	for _, snap := range trends.Series {
+		if snap.FreeSpace > 0 {
+			freePoints = append(freePoints, svgchart.Point{Time: snap.StartTime, Value: float64(snap.FreeSpace)})
+		}
+	}
+	if chart := sizeChart("free space", freePoints, "#0dbc79"); chart != "" {
+		data.Charts = append(data.Charts, chart)
+	}
+
+	for i, info := range curr.InfoList {
+		var prevInfo DirInfoStruct
+		if i < len(prev.InfoList) {
+			prevInfo = prev.InfoList[i]
+		}
+		growth := trends.Dirs[info.Path]
+		row := dirRow{
+			Path:   shorifyPath(info.Path),
+			Size:   HumanSize(info.Size),
+			Dirs:   fmt.Sprint(info.Dirs),
+			Files:  fmt.Sprint(info.Files),
+			Growth: stripansi.Strip(growth.PerDay(growth.Rate)),
+			FullIn: stripansi.Strip(growth.FullInString()),
+		}
+		if prevInfo.Path != "" && prevInfo.Size != info.Size {
+			row.Delta = HumanSizeSign(info.Size - prevInfo.Size)
+		}
+		data.Dirs = append(data.Dirs, row)
+
+		var points []svgchart.Point
+		for _, point := range trends.History[info.Path] {
+			points = append(points, svgchart.Point(point))
+		}
+		if chart := sizeChart(shorifyPath(info.Path), points, ""); chart != "" {
+			data.Charts = append(data.Charts, chart)
+		}
+
+		if changes := Diff(prevInfo, info); len(changes) > 0 {
+			data.Trees = append(data.Trees, tree{Path: shorifyPath(info.Path), Count: len(changes), Tree: changesTree(info.Path, changes)})
+		}
+	}
+
+	var buffer bytes.Buffer
+	err := htmlReportTemplate.Execute(&buffer, data)
+	return buffer.Bytes(), err
+}
+
+// HTMLIndex returns HTML list of all snapshots with links to their reports
+func HTMLIndex() ([]byte, error) {
+	list, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	type row struct{ Time, Link, FreeSpace, Tags string }
+	data := struct {
+		Title     string
+		Snapshots []row
+		Charts    []template.HTML
+	}{Title: ReportTitle()}
+	var freePoints []svgchart.Point
+	for i := len(list) - 1; i >= 0; i-- {
+		snap := list[i]
+		r := row{Time: snap.StartTime.Format("02 Jan 2006 15:04:05"), FreeSpace: HumanSize(snap.FreeSpace), Tags: strings.Join(snap.Tags, ", ")}
+		if reportDir := gStore.ReportDir(snap); reportDir != "" {
+			if _, err := os.Stat(reportDir + "/report.html"); err == nil {
+				if rel, err := filepath.Rel(gDataDir, reportDir+"/report.html"); err == nil {
+					r.Link = (&url.URL{Path: "./" + filepath.ToSlash(rel)}).String()
+				}
+			}
+		}
+		data.Snapshots = append(data.Snapshots, r)
+		if snap.FreeSpace > 0 {
+			freePoints = append([]svgchart.Point{{Time: snap.StartTime, Value: float64(snap.FreeSpace)}}, freePoints...)
+		}
+	}
+	if chart := sizeChart("free space", freePoints, "#0dbc79"); chart != "" {
+		data.Charts = append(data.Charts, chart)
+	}
+	var buffer bytes.Buffer
+	err = htmlIndexTemplate.Execute(&buffer, data)
+	return buffer.Bytes(), err
+}
+
+// SaveHTMLReport writes report.html of the run to the snapshot report dir
+func SaveHTMLReport(prev, curr SnapshotStruct, alerts []Alert, trends Trends) {
+	reportDir := gStore.ReportDir(curr)
+	if reportDir == "" {
+		return // the store doesn't keep reports
+	}
+	indexLink, _ := filepath.Rel(reportDir, gDataDir+"/index.html")
+	content, err := HTMLReport(prev, curr, alerts, trends, filepath.ToSlash(indexLink))
+	if err == nil {
+		err = os.WriteFile(reportDir+"/report.html", content, 0666)
+	}
+	if err != nil {
+		LogErr("html report:", err)
+	}
+}
+
+// SaveHTMLIndex writes index.html of all snapshots to the data dir
+func SaveHTMLIndex() {
+	if gStore.ReportDir(SnapshotStruct{ID: store.NewSnapshotID(gStartTime)}) == "" {
+		return
+	}
+	content, err := HTMLIndex()
+	if err == nil {
+		err = os.WriteFile(gDataDir+"/index.html", content, 0666)
+	}
+	if err != nil {
+		LogErr("html index:", err)
+	}
+}
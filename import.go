@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"path/filepath"
+	"space-monitor/libs/du"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/ncdu"
+	"space-monitor/libs/store"
+	"strings"
+	"time"
+)
+
+// ImportedTag pins imported snapshots (with -pin), so the retention policy doesn't delete the old data
+const ImportedTag = "imported"
+
+// ReadNcduDump reads ncdu JSON export. Returns the root path, the file map and the export time
+func ReadNcduDump(data []byte) (string, map[string]GobFileInfo, time.Time, error) {
+	root, timestamp, err := ncdu.Read(bytes.NewReader(data))
+	if err != nil {
+		return "", nil, timestamp, err
+	}
+	fileMap := map[string]GobFileInfo{}
+	var walk func(entry *ncdu.Entry, path string)
+	walk = func(entry *ncdu.Entry, path string) {
+		if !entry.IsDir {
+			fileMap[path] = GobFileInfo{Size: entry.Size}
+			return
+		}
+		fileMap[path] = GobFileInfo{IsDir: true}
+		for _, child := range entry.Children {
+			walk(child, filepath.Join(path, child.Name))
+		}
+	}
+	rootPath := filepath.Clean(root.Name)
+	walk(root, rootPath)
+	return rootPath, fileMap, timestamp, nil
+}
+
+// ParseImportTime parses the -time flag value in the local time zone
+func ParseImportTime(str string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, strings.TrimSpace(str), time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected YYYY-MM-DD [HH:MM[:SS]]", str)
+}
+
+// ImportDump imports the 'du -ab' output or ncdu JSON export file as the directory info of a snapshot.
+// The snapshot time is the ncdu export time or the modification time of the du output file, unless set by -time.
+// A snapshot of the same time gets the directory added, unless it already has it (or a directory overlapping it)
+func ImportDump(file string, dryRun bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	stat, err := os.Stat(file)
+	if err != nil {
+		return err
+	}
+	format := "du"
+	startTime := stat.ModTime()
+	var root string
+	var fileMap map[string]GobFileInfo
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		format = "ncdu"
+		var timestamp time.Time
+		root, fileMap, timestamp, err = ReadNcduDump(data)
+		if !timestamp.IsZero() {
+			startTime = timestamp
+		}
+	} else {
+		root, fileMap, err = du.ReadFileMap(bytes.NewReader(data))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	if *gTime != "" {
+		if startTime, err = ParseImportTime(*gTime); err != nil {
+			return err
+		}
+	}
+
+	// the dump may describe the directory under another path (eg. relative 'du -ab .' output)
+	if *gRoot != "" {
+		target, err := filepath.Abs(AbsPath(*gRoot))
+		if err != nil {
+			return err
+		}
+		moved := map[string]GobFileInfo{}
+		for path, info := range fileMap {
+			if rel, err := filepath.Rel(root, path); err == nil {
+				moved[filepath.Join(target, rel)] = info
+			}
+		}
+		root, fileMap = target, moved
+	}
+	if !filepath.IsAbs(root) {
+		return fmt.Errorf("%s: root path %q is relative, set the directory with -root", file, root)
+	}
+	if dir, ok := FindDirSettings(root); !ok || AbsPath(dir.Path) != root {
+		return fmt.Errorf("%s: %s is not a configured directory (use -root to import it as one)", file, root)
+	}
+
+	info := store.NewImportedDirInfo(root, fileMap)
+	info.StartTime = startTime
+	snapshot := SnapshotStruct{StartTime: startTime, ID: store.NewSnapshotID(startTime)}
+	if existing, err := gStore.Load(snapshot.ID); err == nil {
+		// dumps of other directories made at the same time are imported into the same snapshot
+		if overlap, ok := findOverlappingDir(existing, root); ok {
+			return fmt.Errorf("%s: snapshot %s already has %s (set another time with -time)", file, snapshot.ID, shorifyPath(overlap))
+		}
+		snapshot = existing
+	}
+	if *gPin {
+		snapshot.AddTag(ImportedTag)
+	}
+
+	fmt2.Printf(" %s %s: %s %s, %d files, %d dirs -> snapshot %s\n", ColorHeader("%-4s", format), file,
+		color.HiBlueString(shorifyPath(root)), HumanSize(info.Size), info.Files, info.Dirs, snapshot.ID)
+	if dryRun {
+		return nil
+	}
+	if err := gStore.Save(snapshot); err != nil {
+		return err
+	}
+	return gStore.SaveDirInfo(snapshot, info)
+}
+
+// findOverlappingDir returns the configured directory stored in the snapshot that contains the root or is inside it
+func findOverlappingDir(snapshot SnapshotStruct, root string) (string, bool) {
+	for _, dir := range gCfg.Dirs {
+		path := AbsPath(dir.Path)
+		if !store.IsSubPath(path, root) && !store.IsSubPath(root, path) {
+			continue
+		}
+		if _, err := gStore.LoadDirInfo(snapshot, path, false); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// CommandImport imports 'du -ab' outputs and ncdu JSON exports as snapshots
+func CommandImport(files []string, dryRun bool) int {
+	if len(files) == 0 {
+		LogErr("usage: import <file>... [-root /dir] [-time 2021-03-01] [-pin] [-dry-run]")
+		return 1
+	}
+	if len(files) > 1 && *gTime != "" {
+		LogErr("-time can be set only for a single imported file")
+		return 1
+	}
+	code := 0
+	for _, file := range files {
+		if err := ImportDump(file, dryRun); err != nil {
+			LogErr(err)
+			code = 1
+		}
+	}
+	return code
+}
@@ -0,0 +1,133 @@
+// Package age buckets file ages for histograms and finds stale subtrees
+package age
+
+import (
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/store"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// Bucket is a histogram bucket of ages below the limit (the last bucket has no limit)
+type Bucket struct {
+	Name  string
+	Limit time.Duration
+}
+
+// Buckets of the histogram from the youngest to the oldest
+var Buckets = []Bucket{
+	{"< 1 day", day},
+	{"< 1 week", 7 * day},
+	{"< 1 month", 30 * day},
+	{"< 3 months", 91 * day},
+	{"< 6 months", 182 * day},
+	{"< 1 year", 365 * day},
+	{"< 2 years", 2 * 365 * day},
+	{"< 5 years", 5 * 365 * day},
+	{">= 5 years", 0},
+}
+
+// BucketOf returns the name of the bucket of the age. Negative ages (times in the future) belong to the first bucket
+func BucketOf(age time.Duration) string {
+	for _, bucket := range Buckets[:len(Buckets)-1] {
+		if age < bucket.Limit {
+			return bucket.Name
+		}
+	}
+	return Buckets[len(Buckets)-1].Name
+}
+
+// StaleTracker finds the largest topmost stale subtrees (their parent is not stale) during a walk in lexical
+// order (filepath.Walk). Only the subtrees on the path to the current file are open, finished ones are folded
+// into their parents, so memory depends on the depth of the tree and not on the number of directories
+type StaleTracker struct {
+	root   string
+	cutoff time.Time
+	top    int
+	open   []openSubtree // from the root to the directory of the last file
+	stale  []store.StaleSubtree
+}
+
+type openSubtree struct {
+	store.StaleSubtree
+	stale []store.StaleSubtree // the largest topmost stale subtrees of the finished children
+}
+
+// NewStaleTracker creates the tracker of the walk of the root. Subtrees with no file modified since
+// the cutoff are stale, the top largest ones are kept
+func NewStaleTracker(root string, cutoff time.Time, top int) *StaleTracker {
+	return &StaleTracker{root: root, cutoff: cutoff, top: top}
+}
+
+// Add counts the file in its directory. Directories the walk has left are finished
+func (t *StaleTracker) Add(path string, size int64, mtime time.Time) {
+	dir := filepath.Dir(path)
+	if !store.IsSubPath(dir, t.root) {
+		return // the root is a file
+	}
+	for len(t.open) > 0 && !store.IsSubPath(dir, t.open[len(t.open)-1].Path) {
+		t.close()
+	}
+	var missing []string
+	for current := dir; len(t.open) == 0 || current != t.open[len(t.open)-1].Path; current = filepath.Dir(current) {
+		missing = append(missing, current)
+		if current == t.root {
+			break
+		}
+	}
+	for i := len(missing) - 1; i >= 0; i-- {
+		t.open = append(t.open, openSubtree{StaleSubtree: store.StaleSubtree{Path: missing[i]}})
+	}
+
+	current := &t.open[len(t.open)-1]
+	current.Size += size
+	current.Files++
+	if mtime.After(current.Newest) {
+		current.Newest = mtime
+	}
+}
+
+// close finishes the innermost open subtree and folds it into its parent
+func (t *StaleTracker) close() {
+	subtree := t.open[len(t.open)-1]
+	t.open = t.open[:len(t.open)-1]
+	stale := subtree.stale
+	if subtree.Files > 0 && subtree.Newest.Before(t.cutoff) {
+		stale = []store.StaleSubtree{subtree.StaleSubtree} // replaces its stale children
+	}
+	if len(t.open) == 0 {
+		t.stale = largest(append(t.stale, stale...), t.top)
+		return
+	}
+	parent := &t.open[len(t.open)-1]
+	parent.Size += subtree.Size
+	parent.Files += subtree.Files
+	if subtree.Newest.After(parent.Newest) {
+		parent.Newest = subtree.Newest
+	}
+	parent.stale = largest(append(parent.stale, stale...), t.top)
+}
+
+// Finish finishes all open subtrees and returns the largest topmost stale ones
+func (t *StaleTracker) Finish() []store.StaleSubtree {
+	for len(t.open) > 0 {
+		t.close()
+	}
+	return t.stale
+}
+
+// largest sorts the subtrees by size (larger first) and keeps the top ones
+func largest(subtrees []store.StaleSubtree, top int) []store.StaleSubtree {
+	sort.Slice(subtrees, func(i, j int) bool {
+		if subtrees[i].Size != subtrees[j].Size {
+			return subtrees[i].Size > subtrees[j].Size
+		}
+		return subtrees[i].Path < subtrees[j].Path
+	})
+	if len(subtrees) > top {
+		subtrees = subtrees[:top]
+	}
+	return subtrees
+}
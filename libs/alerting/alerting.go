@@ -0,0 +1,258 @@
+// Package alerting evaluates the alert rules against the snapshots of the runs
+package alerting
+
+import (
+	"fmt"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
+	"strconv"
+	"strings"
+)
+
+// Level is the alert severity. Values are process exit codes (Nagios plugin convention)
+type Level int
+
+const (
+	OK       Level = 0
+	Warning  Level = 1
+	Critical Level = 2
+	Unknown  Level = 3 // the condition can't be checked (or the run failed)
+)
+
+func (l Level) String() string {
+	switch l {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	}
+	return "UNKNOWN"
+}
+
+// Alert is a triggered alert rule condition
+type Alert struct {
+	Level   Level
+	Rule    string
+	Message string
+}
+
+// MaxLevel returns the highest level of the alerts
+func MaxLevel(alerts []Alert) Level {
+	level := OK
+	for _, alert := range alerts {
+		if alert.Level > level {
+			level = alert.Level
+		}
+	}
+	return level
+}
+
+// Threshold is an absolute or percentage limit
+type Threshold struct {
+	Value   float64
+	Percent bool
+}
+
+// ParseThreshold parses thresholds like "10G", "5%" or "1000" (with sizes set, the value is a size)
+func ParseThreshold(str string, size bool) (Threshold, error) {
+	str = strings.TrimSpace(str)
+	if strings.HasSuffix(str, "%") {
+		value, err := strconv.ParseFloat(strings.TrimSuffix(str, "%"), 64)
+		if err != nil || value < 0 || value > 100 {
+			return Threshold{}, fmt.Errorf("invalid percentage %q", str)
+		}
+		return Threshold{Value: value, Percent: true}, nil
+	}
+	if size {
+		value, err := human.ParseSize(str)
+		return Threshold{Value: float64(value)}, err
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return Threshold{}, fmt.Errorf("invalid number %q", str)
+	}
+	return Threshold{Value: value}, nil
+}
+
+// Limit returns the absolute limit for the total amount
+func (t Threshold) Limit(total float64) float64 {
+	if t.Percent {
+		return total * t.Value / 100
+	}
+	return t.Value
+}
+
+// Rule alert rule. All set conditions are checked independently
+type Rule struct {
+	Name              string `yaml:"name"`
+	Level             string `yaml:"level"`                // warning (default) or critical
+	Path              string `yaml:"path"`                 // configured directory (size, growth) or any path of the mount (free space, inodes). Empty means all mounts
+	FreeBelow         string `yaml:"free-below"`           // free space of the mount, bytes (eg. 10G) or percent (eg. 5%)
+	InodesFreeBelow   string `yaml:"inodes-free-below"`    // free inodes of the mount, count or percent
+	SizeAbove         string `yaml:"size-above"`           // directory size
+	GrowthPerRunAbove string `yaml:"growth-per-run-above"` // directory (or used space) growth since the previous run
+	GrowthPerDayAbove string `yaml:"growth-per-day-above"` // directory (or used space) growth trend per day
+}
+
+// Validate checks the alert rule
+func (r Rule) Validate() error {
+	if r.Level != "" && r.Level != "warning" && r.Level != "critical" {
+		return fmt.Errorf("alert %s: unknown level %q (warning or critical expected)", r.Title(), r.Level)
+	}
+	for _, t := range []struct {
+		value string
+		size  bool
+	}{{r.FreeBelow, true}, {r.InodesFreeBelow, false}} {
+		if t.value != "" {
+			if _, err := ParseThreshold(t.value, t.size); err != nil {
+				return fmt.Errorf("alert %s: %w", r.Title(), err)
+			}
+		}
+	}
+	for _, value := range []string{r.SizeAbove, r.GrowthPerRunAbove, r.GrowthPerDayAbove} {
+		if value != "" {
+			if _, err := human.ParseSize(value); err != nil {
+				return fmt.Errorf("alert %s: %w", r.Title(), err)
+			}
+		}
+	}
+	if r.SizeAbove != "" && r.Path == "" {
+		return fmt.Errorf("alert %s: size-above requires path", r.Title())
+	}
+	return nil
+}
+
+// Title returns the rule name for messages
+func (r Rule) Title() string {
+	if r.Name != "" {
+		return r.Name
+	}
+	if r.Path != "" {
+		return r.Path
+	}
+	return "all mounts"
+}
+
+// AlertLevel returns severity of the rule
+func (r Rule) AlertLevel() Level {
+	if r.Level == "critical" {
+		return Critical
+	}
+	return Warning
+}
+
+// Rates are the growth trends (bytes per day) of the run. Missing values mean there are not enough samples
+type Rates struct {
+	Dirs      map[string]float64 // by directory path
+	Used      float64            // used space of all mounts
+	UsedValid bool
+}
+
+// findDirInfo returns current and previous info of the configured directory
+func findDirInfo(prev, curr store.Snapshot, path string) (store.DirInfo, store.DirInfo, bool) {
+	for _, info := range curr.InfoList {
+		if info.Path == path {
+			for _, prevInfo := range prev.InfoList {
+				if prevInfo.Path == path {
+					return prevInfo, info, true
+				}
+			}
+			return store.DirInfo{}, info, true
+		}
+	}
+	return store.DirInfo{}, store.DirInfo{}, false
+}
+
+// Evaluate checks the rules against the current run results. Rates are the growth trends of the
+// current snapshot, absPath resolves the rule paths (eg. "~/")
+func Evaluate(rules []Rule, prev, curr store.Snapshot, rates Rates, absPath func(string) string) []Alert {
+	var alerts []Alert
+	for _, rule := range rules {
+		raiseLevel := func(level Level, format string, a ...any) {
+			alerts = append(alerts, Alert{Level: level, Rule: rule.Title(), Message: fmt.Sprintf(format, a...)})
+		}
+		raise := func(format string, a ...any) {
+			raiseLevel(rule.AlertLevel(), format, a...)
+		}
+		path := absPath(rule.Path)
+
+		// mount conditions
+		var mounts []store.MountUsage
+		if rule.Path == "" {
+			mounts = curr.Mounts
+		} else if mount, ok := curr.FindMount(path); ok {
+			mounts = append(mounts, mount)
+		} else if rule.FreeBelow != "" || rule.InodesFreeBelow != "" {
+			// the free space of the snapshot is of another filesystem (the data directory)
+			raiseLevel(Unknown, "no mount for %s", rule.Path)
+		}
+		if rule.FreeBelow != "" && (rule.Path == "" || len(mounts) > 0) {
+			threshold, _ := ParseThreshold(rule.FreeBelow, true)
+			switch {
+			case len(mounts) > 0:
+				for _, mount := range mounts {
+					if float64(mount.Free) < threshold.Limit(float64(mount.Total)) {
+						raise("free space of %s %s is below %s", mount.Path, human.Size(mount.Free), rule.FreeBelow)
+					}
+				}
+			case curr.FreeSpace > 0 && !threshold.Percent: // snapshot without mount usage
+				if float64(curr.FreeSpace) < threshold.Value {
+					raise("free space %s is below %s", human.Size(curr.FreeSpace), rule.FreeBelow)
+				}
+			default:
+				raiseLevel(Unknown, "free space can't be checked against %s: no mount usage", rule.FreeBelow)
+			}
+		}
+		if rule.InodesFreeBelow != "" {
+			threshold, _ := ParseThreshold(rule.InodesFreeBelow, false)
+			for _, mount := range mounts {
+				if mount.InodesTotal > 0 && float64(mount.InodesFree) < threshold.Limit(float64(mount.InodesTotal)) {
+					raise("free inodes of %s %d are below %s", mount.Path, mount.InodesFree, rule.InodesFreeBelow)
+				}
+			}
+		}
+
+		// directory conditions
+		if rule.Path != "" {
+			prevInfo, info, ok := findDirInfo(prev, curr, path)
+			if !ok {
+				if rule.SizeAbove != "" || rule.GrowthPerRunAbove != "" || rule.GrowthPerDayAbove != "" {
+					raise("%s is not a configured directory", rule.Path)
+				}
+				continue
+			}
+			if rule.SizeAbove != "" {
+				if limit, _ := human.ParseSize(rule.SizeAbove); info.Size > limit {
+					raise("size of %s %s is above %s", rule.Path, human.Size(info.Size), rule.SizeAbove)
+				}
+			}
+			if rule.GrowthPerRunAbove != "" && prevInfo.Path != "" {
+				if limit, _ := human.ParseSize(rule.GrowthPerRunAbove); info.Size-prevInfo.Size > limit {
+					raise("%s grew by %s since the previous run (above %s)", rule.Path, human.Size(info.Size-prevInfo.Size), rule.GrowthPerRunAbove)
+				}
+			}
+			if rule.GrowthPerDayAbove != "" {
+				rate, ok := rates.Dirs[path]
+				if limit, _ := human.ParseSize(rule.GrowthPerDayAbove); ok && rate > float64(limit) {
+					raise("%s grows by %s per day (above %s)", rule.Path, human.SizeSign(int64(rate)), rule.GrowthPerDayAbove)
+				}
+			}
+			continue
+		}
+
+		// used space conditions (all mounts)
+		if rule.GrowthPerRunAbove != "" && prev.FreeSpace > 0 {
+			if limit, _ := human.ParseSize(rule.GrowthPerRunAbove); prev.FreeSpace-curr.FreeSpace > limit {
+				raise("used space grew by %s since the previous run (above %s)", human.Size(prev.FreeSpace-curr.FreeSpace), rule.GrowthPerRunAbove)
+			}
+		}
+		if rule.GrowthPerDayAbove != "" && rates.UsedValid {
+			if limit, _ := human.ParseSize(rule.GrowthPerDayAbove); rates.Used > float64(limit) {
+				raise("used space grows by %s per day (above %s)", human.SizeSign(int64(rates.Used)), rule.GrowthPerDayAbove)
+			}
+		}
+	}
+	return alerts
+}
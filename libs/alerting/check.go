@@ -0,0 +1,92 @@
+package alerting
+
+import (
+	"fmt"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
+	"strings"
+)
+
+// perfThresholds returns perfdata warning and critical values of the rules applicable to the path. Of overlapping
+// rules the strictest limit is used: the highest one for free space (below) and the lowest one for sizes (above)
+func perfThresholds(rules []Rule, matches func(rule Rule) (string, bool), total int64, above bool) (string, string) {
+	limits := map[Level]float64{}
+	for _, rule := range rules {
+		value, ok := matches(rule)
+		if !ok {
+			continue
+		}
+		threshold, err := ParseThreshold(value, true)
+		if err != nil {
+			continue
+		}
+		if threshold.Percent && total <= 0 {
+			continue
+		}
+		limit := threshold.Limit(float64(total))
+		if current, ok := limits[rule.AlertLevel()]; !ok || (above && limit < current) || (!above && limit > current) {
+			limits[rule.AlertLevel()] = limit
+		}
+	}
+	format := func(level Level) string {
+		if limit, ok := limits[level]; ok {
+			return fmt.Sprintf("%.0f", limit)
+		}
+		return ""
+	}
+	return format(Warning), format(Critical)
+}
+
+// perfLabel quotes the perfdata label
+func perfLabel(label string) string {
+	return "'" + strings.ReplaceAll(label, "'", "''") + "'"
+}
+
+// CheckOutput returns Nagios plugin status line with perfdata of the snapshot. The thresholds of the rules are
+// added to the perfdata of the mounts (free-below) and directories (size-above), absPath resolves the rule paths.
+// Mounts with unknown total size (eg. some pseudo filesystems) are skipped
+func CheckOutput(level Level, alerts []Alert, rules []Rule, snapshot store.Snapshot, absPath func(string) string) string {
+	var mounts []store.MountUsage
+	for _, mount := range snapshot.Mounts {
+		if mount.Total > 0 {
+			mounts = append(mounts, mount)
+		}
+	}
+	var summary []string
+	for _, alert := range alerts {
+		summary = append(summary, alert.Message)
+	}
+	if len(summary) == 0 {
+		for _, mount := range mounts {
+			summary = append(summary, fmt.Sprintf("%s free on %s (%.0f%%)", human.Size(mount.Free), mount.Path, 100*float64(mount.Free)/float64(mount.Total)))
+		}
+		if len(mounts) == 0 {
+			summary = append(summary, human.Size(snapshot.FreeSpace)+" free")
+		}
+	}
+
+	var perf []string
+	for _, mount := range mounts {
+		mount := mount
+		warn, crit := perfThresholds(rules, func(rule Rule) (string, bool) {
+			ruleMount, _ := snapshot.FindMount(absPath(rule.Path))
+			applies := rule.Path == "" || ruleMount.Path == mount.Path
+			return rule.FreeBelow, applies && rule.FreeBelow != ""
+		}, mount.Total, false)
+		perf = append(perf, fmt.Sprintf("%s=%dB;%s;%s;0;%d", perfLabel("free "+mount.Path), mount.Free, warn, crit, mount.Total))
+	}
+	if len(mounts) == 0 {
+		perf = append(perf, fmt.Sprintf("%s=%dB;;;0;", perfLabel("free"), snapshot.FreeSpace))
+	}
+	for _, info := range snapshot.InfoList {
+		if info.Path == "" {
+			continue
+		}
+		info := info
+		warn, crit := perfThresholds(rules, func(rule Rule) (string, bool) {
+			return rule.SizeAbove, rule.SizeAbove != "" && absPath(rule.Path) == info.Path
+		}, 0, true)
+		perf = append(perf, fmt.Sprintf("%s=%dB;%s;%s;0;", perfLabel(info.Path), info.Size, warn, crit))
+	}
+	return fmt.Sprintf("SPACE-MONITOR %s - %s | %s", level, strings.Join(summary, "; "), strings.Join(perf, " "))
+}
@@ -0,0 +1,141 @@
+// Package ansihtml converts ANSI-colored terminal output to HTML
+package ansihtml
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// Palette of the 16 standard terminal colors (normal 0-7, bright 8-15)
+var Palette = [16]string{
+	"#000000", "#cd3131", "#0dbc79", "#e5e510", "#2472c8", "#bc3fbc", "#11a8cd", "#e5e5e5",
+	"#666666", "#f14c4c", "#23d18b", "#f5f543", "#3b8eea", "#d670d6", "#29b8db", "#ffffff",
+}
+
+type style struct {
+	fg, bg                         int // palette index + 1, zero means default
+	bold, faint, italic, underline bool
+}
+
+func (s style) css() string {
+	var parts []string
+	if s.fg > 0 {
+		parts = append(parts, "color:"+Palette[s.fg-1])
+	}
+	if s.bg > 0 {
+		parts = append(parts, "background-color:"+Palette[s.bg-1])
+	}
+	if s.bold {
+		parts = append(parts, "font-weight:bold")
+	}
+	if s.faint {
+		parts = append(parts, "opacity:0.6")
+	}
+	if s.italic {
+		parts = append(parts, "font-style:italic")
+	}
+	if s.underline {
+		parts = append(parts, "text-decoration:underline")
+	}
+	return strings.Join(parts, ";")
+}
+
+// apply applies SGR parameters to the style
+func (s style) apply(params string) style {
+	if params == "" {
+		return style{}
+	}
+	codes := strings.Split(params, ";")
+	for i := 0; i < len(codes); i++ {
+		code, err := strconv.Atoi(codes[i])
+		if err != nil {
+			continue
+		}
+		switch {
+		case code == 0:
+			s = style{}
+		case code == 1:
+			s.bold = true
+		case code == 2:
+			s.faint = true
+		case code == 3:
+			s.italic = true
+		case code == 4:
+			s.underline = true
+		case code == 22:
+			s.bold, s.faint = false, false
+		case code == 23:
+			s.italic = false
+		case code == 24:
+			s.underline = false
+		case code >= 30 && code <= 37:
+			s.fg = code - 30 + 1
+		case code == 39:
+			s.fg = 0
+		case code >= 40 && code <= 47:
+			s.bg = code - 40 + 1
+		case code == 49:
+			s.bg = 0
+		case code >= 90 && code <= 97:
+			s.fg = code - 90 + 8 + 1
+		case code >= 100 && code <= 107:
+			s.bg = code - 100 + 8 + 1
+		case code == 38 || code == 48: // extended colors are not supported, skip their arguments
+			if i+1 < len(codes) && codes[i+1] == "5" {
+				i += 2
+			} else if i+1 < len(codes) && codes[i+1] == "2" {
+				i += 4
+			}
+		}
+	}
+	return s
+}
+
+// Convert returns HTML of the ANSI-colored text (without the enclosing <pre> element).
+// Unsupported escape sequences are dropped
+func Convert(text string) string {
+	var sb strings.Builder
+	var current, written style // style of the text and style of the open span
+	write := func(str string) {
+		if str == "" {
+			return
+		}
+		if current != written {
+			if written != (style{}) {
+				sb.WriteString("</span>")
+			}
+			if css := current.css(); css != "" {
+				fmt.Fprintf(&sb, `<span style="%s">`, css)
+			}
+			written = current
+		}
+		sb.WriteString(html.EscapeString(str))
+	}
+	for len(text) > 0 {
+		index := strings.IndexByte(text, '\x1b')
+		if index < 0 {
+			write(text)
+			break
+		}
+		write(text[:index])
+		text = text[index+1:]
+		if !strings.HasPrefix(text, "[") {
+			continue
+		}
+		end := strings.IndexFunc(text[1:], func(r rune) bool { return r >= 0x40 && r <= 0x7e })
+		if end < 0 {
+			break // truncated sequence
+		}
+		params, final := text[1:end+1], text[end+1]
+		text = text[end+2:]
+		if final == 'm' {
+			current = current.apply(params)
+		}
+	}
+	if written != (style{}) {
+		sb.WriteString("</span>")
+	}
+	return sb.String()
+}
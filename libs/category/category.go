@@ -0,0 +1,110 @@
+// Package category classifies files into categories (video, logs, archives...) by extensions and name patterns
+package category
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/store"
+	"strings"
+)
+
+// NoExtension is the extension of files without one
+const NoExtension = "(none)"
+
+// OtherExtensions is the sum of the extensions not kept by Top
+const OtherExtensions = "(other)"
+
+// maximal length of an extension, longer ones are usually not extensions (eg. "file.20230101T1200")
+const maxExtensionLen = 10
+
+// Matcher classifies file names into categories
+type Matcher struct {
+	extensions map[string]string // lowercase extension -> category
+	patterns   []pattern
+}
+
+type pattern struct {
+	glob     string
+	category string
+}
+
+// New compiles categories: a list of extensions ("mp4", ".mkv") or name patterns ("*.log.*") for each category name.
+// Patterns are checked before extensions; categories are checked in alphabetical order
+func New(categories map[string][]string) (*Matcher, error) {
+	m := &Matcher{extensions: map[string]string{}}
+	var names []string
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, item := range categories[name] {
+			item = strings.ToLower(strings.TrimSpace(item))
+			if strings.ContainsAny(item, "*?[") {
+				if _, err := filepath.Match(item, ""); err != nil {
+					return nil, fmt.Errorf("category %s: invalid pattern %q: %w", name, item, err)
+				}
+				m.patterns = append(m.patterns, pattern{glob: item, category: name})
+				continue
+			}
+			item = strings.TrimPrefix(item, ".")
+			if item == "" {
+				return nil, fmt.Errorf("category %s: empty extension", name)
+			}
+			if _, ok := m.extensions[item]; !ok {
+				m.extensions[item] = name
+			}
+		}
+	}
+	return m, nil
+}
+
+// Match returns the category of the file name (empty if none matches)
+func (m *Matcher) Match(fileName string) string {
+	lower := strings.ToLower(fileName)
+	for _, p := range m.patterns {
+		if ok, _ := filepath.Match(p.glob, lower); ok {
+			return p.category
+		}
+	}
+	return m.extensions[Extension(fileName)]
+}
+
+// Extension returns the lowercase extension of the file name without the dot (NoExtension if there is none)
+func Extension(fileName string) string {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(fileName), "."))
+	if ext == "" || len(ext) > maxExtensionLen || ext == strings.TrimPrefix(strings.ToLower(fileName), ".") {
+		return NoExtension // no extension, too long to be an extension or a dot file (".bashrc")
+	}
+	return ext
+}
+
+// Top returns the n largest extensions of the breakdown, the rest is summed into OtherExtensions
+func Top(stats map[string]store.UsageStat, n int) map[string]store.UsageStat {
+	if len(stats) <= n {
+		return stats
+	}
+	keys := make([]string, 0, len(stats))
+	for key := range stats {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if stats[keys[i]].Size != stats[keys[j]].Size {
+			return stats[keys[i]].Size > stats[keys[j]].Size
+		}
+		return keys[i] < keys[j]
+	})
+	top := map[string]store.UsageStat{}
+	for i, key := range keys {
+		name := key
+		if i >= n || key == OtherExtensions {
+			name = OtherExtensions
+		}
+		stat := top[name]
+		stat.Size += stats[key].Size
+		stat.Files += stats[key].Files
+		top[name] = stat
+	}
+	return top
+}
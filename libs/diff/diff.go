@@ -0,0 +1,119 @@
+// Package diff compares the file maps of two scans of a directory
+package diff
+
+import (
+	"bytes"
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/store"
+)
+
+// Type is the kind of the change
+type Type int
+
+const (
+	Added Type = iota
+	Modified
+	Deleted
+)
+
+// Change is a file or directory changed between the scans
+type Change struct {
+	Path  string
+	Type  Type
+	File  store.FileInfo // current entry (previous for deleted ones)
+	Delta int64          // size change
+}
+
+// Files collects the changes between the file maps sorted by path. An empty previous map (eg. first run) has no changes.
+// Entries of the same size are modified if both have content digests and they differ
+func Files(prevMap, currMap map[string]store.FileInfo) []Change {
+	if len(prevMap) == 0 {
+		return []Change{}
+	}
+
+	var changes []Change
+	for path, curr := range currMap {
+		prev, ok := prevMap[path]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Path: path, Type: Added, File: curr, Delta: curr.Size})
+		case curr.Size != prev.Size:
+			changes = append(changes, Change{Path: path, Type: Modified, File: curr, Delta: curr.Size - prev.Size})
+		case len(curr.Hash) > 0 && len(prev.Hash) > 0 && !bytes.Equal(curr.Hash, prev.Hash):
+			changes = append(changes, Change{Path: path, Type: Modified, File: curr}) // same size but content is changed
+		}
+	}
+	for path, prev := range prevMap {
+		if _, ok := currMap[path]; !ok {
+			changes = append(changes, Change{Path: path, Type: Deleted, File: prev, Delta: -prev.Size})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes
+}
+
+// Entry is a child of the directory compared between the scans
+type Entry struct {
+	Path    string
+	IsDir   bool
+	Size    int64
+	Delta   int64
+	Added   bool
+	Deleted bool
+}
+
+// Index returns the children of each directory of the file map
+func Index(fileMap map[string]store.FileInfo, root string) map[string][]string {
+	children := map[string][]string{}
+	for path := range fileMap {
+		if path != root {
+			parent := filepath.Dir(path)
+			children[parent] = append(children[parent], path)
+		}
+	}
+	return children
+}
+
+// Children compares the children of the directory in the current and the previous file map (with their indexes).
+// Without the previous map (hasPrev is false) nothing is added or deleted
+func Children(currMap, prevMap map[string]store.FileInfo, currIndex, prevIndex map[string][]string, dir string, hasPrev bool) []Entry {
+	var entries []Entry
+	for _, path := range currIndex[dir] {
+		info := currMap[path]
+		entry := Entry{Path: path, IsDir: info.IsDir, Size: info.Size}
+		if prevInfo, ok := prevMap[path]; ok {
+			entry.Delta = info.Size - prevInfo.Size
+		} else if hasPrev {
+			entry.Added, entry.Delta = true, info.Size
+		}
+		entries = append(entries, entry)
+	}
+	for _, path := range prevIndex[dir] {
+		if _, ok := currMap[path]; !ok {
+			info := prevMap[path]
+			entries = append(entries, Entry{Path: path, IsDir: info.IsDir, Delta: -info.Size, Deleted: true})
+		}
+	}
+	return entries
+}
+
+// SortEntries sorts the entries by size (larger first) or by the absolute delta first, then by path
+func SortEntries(entries []Entry, byDelta bool) {
+	abs := func(n int64) int64 {
+		if n < 0 {
+			return -n
+		}
+		return n
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if byDelta && entries[i].Delta != entries[j].Delta {
+			return abs(entries[i].Delta) > abs(entries[j].Delta)
+		}
+		if entries[i].Size != entries[j].Size {
+			return entries[i].Size > entries[j].Size
+		}
+		return entries[i].Path < entries[j].Path
+	})
+}
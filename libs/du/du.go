@@ -0,0 +1,70 @@
+// Package du parses output of the 'du -ab' command
+package du
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"space-monitor/libs/store"
+	"strconv"
+	"strings"
+)
+
+// Entry is a line of du output: size in bytes and path. Sizes of directories include their contents
+type Entry struct {
+	Path string
+	Size int64
+}
+
+// Read parses "<size>\t<path>" lines of du output. Empty lines are skipped
+func Read(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if text == "" {
+			continue
+		}
+		sizeStr, path, ok := strings.Cut(text, "\t")
+		if !ok {
+			return nil, fmt.Errorf("du: line %d: expected <size>\\t<path>", line)
+		}
+		size, err := strconv.ParseInt(strings.TrimSpace(sizeStr), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("du: line %d: invalid size %q (is it 'du -ab' output?)", line, sizeStr)
+		}
+		entries = append(entries, Entry{Path: path, Size: size})
+	}
+	return entries, scanner.Err()
+}
+
+// ReadFileMap reads du output into the file map. Returns the root path (printed last by du) and the file map.
+// Paths being parents of other paths are directories, the rest are files (so empty directories become files)
+func ReadFileMap(r io.Reader) (string, map[string]store.FileInfo, error) {
+	entries, err := Read(r)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(entries) == 0 {
+		return "", nil, errors.New("empty du output")
+	}
+	dirs := map[string]bool{}
+	for _, entry := range entries {
+		dirs[filepath.Dir(filepath.Clean(entry.Path))] = true
+	}
+	fileMap := map[string]store.FileInfo{}
+	for _, entry := range entries {
+		path := filepath.Clean(entry.Path)
+		if dirs[path] {
+			fileMap[path] = store.FileInfo{IsDir: true}
+		} else {
+			fileMap[path] = store.FileInfo{Size: entry.Size}
+		}
+	}
+	root := filepath.Clean(entries[len(entries)-1].Path)
+	fileMap[root] = store.FileInfo{IsDir: true}
+	return root, fileMap, nil
+}
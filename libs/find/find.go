@@ -0,0 +1,90 @@
+// Package find searches paths in the file maps of the snapshots
+package find
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"space-monitor/libs/store"
+	"strings"
+)
+
+// Match is a path found in the snapshot file maps
+type Match struct {
+	Path  string
+	IsDir bool
+	Sizes map[int]int64 // snapshot index -> size, only snapshots where the path was seen
+	First int           // index of the first snapshot the path was seen in
+	Last  int           // index of the last snapshot the path was seen in
+}
+
+// PathMatcher returns matcher of paths by the glob or regular expression. Glob without path separators is matched against the base name
+func PathMatcher(pattern string, isRegex bool) (func(path string) bool, error) {
+	if isRegex {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString, nil
+	}
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("bad glob %q: %w", pattern, err)
+	}
+	baseOnly := !strings.ContainsRune(pattern, filepath.Separator)
+	return func(path string) bool {
+		if baseOnly {
+			path = filepath.Base(path)
+		}
+		ok, _ := filepath.Match(pattern, path)
+		return ok
+	}, nil
+}
+
+// Finder collects the paths accepted by the matcher from the file maps of the snapshots
+type Finder struct {
+	match   func(string) bool
+	matches map[string]*Match
+}
+
+// NewFinder creates the finder of the paths accepted by the matcher
+func NewFinder(match func(string) bool) *Finder {
+	return &Finder{match: match, matches: map[string]*Match{}}
+}
+
+// Add collects the matching paths of the file map of the snapshot. Snapshots are added in order, starting with index 0
+func (f *Finder) Add(index int, fileMap map[string]store.FileInfo) {
+	for path, fileInfo := range fileMap {
+		if !f.match(path) {
+			continue
+		}
+		m, ok := f.matches[path]
+		if !ok {
+			m = &Match{Path: path, IsDir: fileInfo.IsDir, Sizes: map[int]int64{}, First: index}
+			f.matches[path] = m
+		}
+		m.Sizes[index] = fileInfo.Size
+		m.Last = index
+	}
+}
+
+// Result returns the paths whose size was within [minSize, maxSize] in at least one snapshot (maxSize <= 0 means
+// no upper bound). Recently appeared paths are first
+func (f *Finder) Result(minSize, maxSize int64) []*Match {
+	var result []*Match
+	for _, m := range f.matches {
+		for _, size := range m.Sizes {
+			if size >= minSize && (maxSize <= 0 || size <= maxSize) {
+				result = append(result, m)
+				break
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].First != result[j].First {
+			return result[i].First > result[j].First // recently appeared first
+		}
+		return result[i].Path < result[j].Path
+	})
+	return result
+}
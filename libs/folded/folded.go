@@ -0,0 +1,49 @@
+// Package folded formats stacks in the "folded" format of flamegraph.pl and speedscope:
+// frames separated by ';', a space and the weight
+package folded
+
+import (
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/store"
+	"strconv"
+	"strings"
+)
+
+// escaper replaces characters having special meaning in the folded format
+var escaper = strings.NewReplacer(";", "_", "\n", " ", "\r", " ")
+
+// Line returns the folded stack line of the frames (root first) with the weight
+func Line(frames []string, weight int64) string {
+	escaped := make([]string, len(frames))
+	for i, frame := range frames {
+		escaped[i] = escaper.Replace(frame)
+	}
+	return strings.Join(escaped, ";") + " " + strconv.FormatInt(weight, 10)
+}
+
+// Stacks returns folded stack lines of the files under the root path weighted by size.
+// If prevMap is set, the weight is the growth since it: shrunk and deleted files are omitted,
+// because flame graph tools don't support negative weights
+func Stacks(fileMap, prevMap map[string]store.FileInfo, root string) []string {
+	var lines []string
+	for path, info := range fileMap {
+		if info.IsDir || !store.IsSubPath(path, root) {
+			continue
+		}
+		weight := info.Size
+		if prevMap != nil {
+			weight -= prevMap[path].Size
+		}
+		if weight <= 0 {
+			continue
+		}
+		frames := []string{root}
+		if rel, err := filepath.Rel(root, path); err == nil && rel != "." {
+			frames = append(frames, strings.Split(rel, string(filepath.Separator))...)
+		}
+		lines = append(lines, Line(frames, weight))
+	}
+	sort.Strings(lines)
+	return lines
+}
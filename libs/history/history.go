@@ -0,0 +1,74 @@
+// Package history collects the time series of a path from the stored snapshots
+package history
+
+import (
+	"errors"
+	"path/filepath"
+	"space-monitor/libs/store"
+	"strings"
+	"time"
+)
+
+// Point is the state of a path in a single snapshot
+type Point struct {
+	Snapshot store.Snapshot
+	Present  bool // false if the path didn't exist at the snapshot time
+	Size     int64
+	Files    int
+}
+
+// SubtreeStats returns total size and number of files of the path according to the file map
+func SubtreeStats(fileMap map[string]store.FileInfo, path string) (size int64, files int, present bool) {
+	info, present := fileMap[path]
+	if !present {
+		return 0, 0, false
+	}
+	if !info.IsDir {
+		return info.Size, 1, true
+	}
+	prefix := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+	for p, i := range fileMap {
+		if !i.IsDir && strings.HasPrefix(p, prefix) {
+			files++
+		}
+	}
+	return info.Size, files, true
+}
+
+// Path collects the time series of the path inside the configured directory dirPath from the snapshots started
+// after 'from'. The configured directory is looked up in its aggregates, nested paths in the detailed file maps
+// (snapshots without them are skipped). Unreadable items are passed to the log function
+func Path(st store.SnapshotStore, snapshots []store.Snapshot, dirPath, path string, from time.Time, log func(v ...any)) ([]Point, error) {
+	infos, err := st.History(dirPath) // snapshots where the configured directory was scanned
+	var skipped *store.SkippedError
+	if errors.As(err, &skipped) {
+		log(err)
+	} else if err != nil {
+		return nil, err
+	}
+	byID := map[string]store.Snapshot{}
+	for _, snap := range snapshots {
+		byID[snap.ID] = snap
+	}
+
+	var points []Point
+	for _, info := range infos {
+		snap, ok := byID[info.SnapshotID]
+		if !ok || snap.StartTime.Before(from) {
+			continue
+		}
+		point := Point{Snapshot: snap}
+		if path == dirPath {
+			point.Present, point.Size, point.Files = true, info.Size, info.Files
+		} else {
+			detailed, err := st.LoadDirInfo(snap, dirPath, true)
+			if err != nil {
+				log("history:", err)
+				continue // no file map in this snapshot (eg. detailed mode was off)
+			}
+			point.Size, point.Files, point.Present = SubtreeStats(detailed.FileMap, path)
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
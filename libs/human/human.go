@@ -0,0 +1,72 @@
+// Package human parses and formats sizes and durations written by humans in the config and command line flags
+package human
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseSize parses size strings like "512", "100K", "1.5G" (binary units) into bytes
+func ParseSize(str string) (int64, error) {
+	str = strings.ToUpper(strings.TrimSpace(str))
+	str = strings.TrimSuffix(strings.TrimSuffix(str, "B"), "I")
+	if str == "" {
+		return 0, errors.New("empty size")
+	}
+	multiplier := 1.0
+	if index := strings.IndexByte("KMGTPE", str[len(str)-1]); index >= 0 {
+		multiplier = math.Pow(1024, float64(index+1))
+		str = str[:len(str)-1]
+	}
+	value, err := strconv.ParseFloat(str, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %q", str)
+	}
+	return int64(value * multiplier), nil
+}
+
+// ParseDuration parses durations like time.ParseDuration does, additionally supporting days, weeks and years ("7d", "2w", "1y")
+func ParseDuration(str string) (time.Duration, error) {
+	str = strings.TrimSpace(str)
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour} {
+		if strings.HasSuffix(str, suffix) {
+			value, err := strconv.ParseFloat(strings.TrimSuffix(str, suffix), 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", str)
+			}
+			return time.Duration(value * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(str)
+}
+
+// Size formats bytes with binary units like "512B" or "1.5G"
+func Size(bytes int64) string {
+	abs := bytes
+	if abs < 0 {
+		abs = -abs
+	}
+	const unit = 1024
+	if abs < unit {
+		return fmt.Sprintf("%dB", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := abs / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%c", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// SizeSign formats bytes like Size does, with the plus sign for positive values
+func SizeSign(bytes int64) string {
+	str := Size(bytes)
+	if !strings.HasPrefix(str, "-") {
+		return "+" + str
+	}
+	return str
+}
@@ -0,0 +1,173 @@
+// Package mail builds multipart (plain text + HTML) messages and sends them via SMTP
+package mail
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Message is an email with plain text and HTML alternatives
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	Text    string // plain text fallback part
+	HTML    string // optional HTML part
+	Date    time.Time
+}
+
+// Bytes returns the message in RFC 5322 format
+func (m Message) Bytes() ([]byte, error) {
+	var buffer bytes.Buffer
+	writer := multipart.NewWriter(&buffer)
+	date := m.Date
+	if date.IsZero() {
+		date = time.Now()
+	}
+	header := []string{
+		"From: " + m.From,
+		"To: " + strings.Join(m.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + date.Format(time.RFC1123Z),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+	buffer.WriteString(strings.Join(header, "\r\n") + "\r\n\r\n")
+
+	parts := []struct{ contentType, body string }{{"text/plain", m.Text}}
+	if m.HTML != "" {
+		parts = append(parts, struct{ contentType, body string }{"text/html", m.HTML}) // the preferred part goes last
+	}
+	for _, part := range parts {
+		partWriter, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=utf-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(partWriter)
+		if _, err := qp.Write([]byte(part.body)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Server is SMTP server settings
+type Server struct {
+	Host     string
+	Port     int
+	Username string // no authentication if empty
+	Password string
+	Security string // starttls (default), tls or none
+	Timeout  time.Duration
+	TLS      *tls.Config // custom TLS settings (eg. trusted certificates), the host certificate is verified if nil
+}
+
+// Validate checks the settings. Passwords are never sent over unencrypted connections except to localhost
+// (net/smtp refuses it), so authentication requires starttls or tls security for remote servers
+func (s Server) Validate() error {
+	if s.Security == "none" && s.Username != "" && !isLocalhost(s.Host) {
+		return fmt.Errorf("smtp authentication to %s requires starttls or tls security", s.Host)
+	}
+	return nil
+}
+
+// isLocalhost returns true for the hosts net/smtp allows plain authentication without TLS
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
+
+// Send sends the message via the SMTP server
+func (s Server) Send(m Message) error {
+	body, err := m.Bytes()
+	if err != nil {
+		return err
+	}
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	address := net.JoinHostPort(s.Host, strconv.Itoa(s.Port))
+	tlsConfig := s.TLS
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{ServerName: s.Host}
+	}
+
+	var conn net.Conn
+	if s.Security == "tls" {
+		conn, err = tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", address, tlsConfig)
+	} else {
+		conn, err = net.DialTimeout("tcp", address, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	_ = conn.SetDeadline(time.Now().Add(timeout))
+	client, err := smtp.NewClient(conn, s.Host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	// noinspection GoUnhandledErrorResult
+	defer client.Close()
+
+	if s.Security == "" || s.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("smtp server doesn't support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if s.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.Username, s.Password, s.Host)); err != nil {
+			return fmt.Errorf("smtp auth: %w", err)
+		}
+	}
+	if err := client.Mail(addressOnly(m.From)); err != nil {
+		return err
+	}
+	for _, to := range m.To {
+		if err := client.Rcpt(addressOnly(to)); err != nil {
+			return err
+		}
+	}
+	data, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := data.Write(body); err != nil {
+		return err
+	}
+	if err := data.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// addressOnly returns the address of "Name <address>"
+func addressOnly(address string) string {
+	if start, end := strings.LastIndex(address, "<"), strings.LastIndex(address, ">"); start >= 0 && end > start {
+		return address[start+1 : end]
+	}
+	return strings.TrimSpace(address)
+}
@@ -0,0 +1,125 @@
+// Package ncdu reads and writes disk usage trees in the ncdu JSON export format (https://dev.yorhel.nl/ncdu/jsonfmt)
+package ncdu
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Entry is a file or a directory of the tree. Size of a directory is its own size, without the children
+type Entry struct {
+	Name     string
+	Size     int64 // apparent size in bytes
+	IsDir    bool
+	Children []*Entry
+}
+
+// header is the metadata object of the export
+type header struct {
+	Progname  string `json:"progname"`
+	Progver   string `json:"progver"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// info is the information object of the entry
+type info struct {
+	Name  string `json:"name"`
+	Asize int64  `json:"asize,omitempty"`
+	Dsize int64  `json:"dsize,omitempty"`
+}
+
+// Write writes the tree as ncdu JSON export. Name of the root should be the absolute path.
+// Disk usage is unknown, so it is reported equal to the apparent size
+func Write(w io.Writer, root *Entry, progname, progver string, timestamp time.Time) error {
+	out := bufio.NewWriter(w)
+	meta, err := json.Marshal(header{Progname: progname, Progver: progver, Timestamp: timestamp.Unix()})
+	if err != nil {
+		return err
+	}
+	out.WriteString("[1,2,")
+	out.Write(meta)
+	out.WriteString(",\n")
+	if err := writeEntry(out, root); err != nil {
+		return err
+	}
+	out.WriteString("]\n")
+	return out.Flush()
+}
+
+func writeEntry(out *bufio.Writer, entry *Entry) error {
+	data, err := json.Marshal(info{Name: entry.Name, Asize: entry.Size, Dsize: entry.Size})
+	if err != nil {
+		return err
+	}
+	if !entry.IsDir {
+		_, err = out.Write(data)
+		return err
+	}
+	out.WriteByte('[')
+	out.Write(data)
+	for _, child := range entry.Children {
+		out.WriteString(",\n")
+		if err := writeEntry(out, child); err != nil {
+			return err
+		}
+	}
+	_, err = out.WriteString("]")
+	return err
+}
+
+// Read parses ncdu JSON export. Returns the tree and the export timestamp (zero if not set)
+func Read(r io.Reader) (*Entry, time.Time, error) {
+	var export []json.RawMessage
+	if err := json.NewDecoder(r).Decode(&export); err != nil {
+		return nil, time.Time{}, err
+	}
+	if len(export) < 4 {
+		return nil, time.Time{}, errors.New("ncdu: not an export: expected [major, minor, metadata, tree]")
+	}
+	var major int
+	if err := json.Unmarshal(export[0], &major); err != nil || major != 1 {
+		return nil, time.Time{}, fmt.Errorf("ncdu: unsupported format version %s", export[0])
+	}
+	var meta header
+	if err := json.Unmarshal(export[2], &meta); err != nil {
+		return nil, time.Time{}, fmt.Errorf("ncdu: metadata: %w", err)
+	}
+	var timestamp time.Time
+	if meta.Timestamp > 0 {
+		timestamp = time.Unix(meta.Timestamp, 0)
+	}
+	root, err := readEntry(export[3])
+	return root, timestamp, err
+}
+
+// readEntry parses a file (info object) or a directory (array of the info object and children)
+func readEntry(data json.RawMessage) (*Entry, error) {
+	var items []json.RawMessage
+	isDir := len(data) > 0 && data[0] == '['
+	if isDir {
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		if len(items) == 0 {
+			return nil, errors.New("ncdu: empty directory array")
+		}
+		data, items = items[0], items[1:]
+	}
+	var i info
+	if err := json.Unmarshal(data, &i); err != nil {
+		return nil, err
+	}
+	entry := &Entry{Name: i.Name, Size: i.Asize, IsDir: isDir}
+	for _, item := range items {
+		child, err := readEntry(item)
+		if err != nil {
+			return nil, err
+		}
+		entry.Children = append(entry.Children, child)
+	}
+	return entry, nil
+}
@@ -0,0 +1,93 @@
+// Package owners checks the disk usage of users and groups against their quotas
+package owners
+
+import (
+	"fmt"
+	"sort"
+	"space-monitor/libs/alerting"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
+	"strings"
+)
+
+// Config settings of the breakdown by file owner
+type Config struct {
+	Enabled bool              `yaml:"enabled"` // collect sizes per owner (not supported on Windows)
+	Groups  bool              `yaml:"groups"`  // also collect sizes per owning group
+	Quotas  map[string]string `yaml:"quotas"`  // size limits of users (or groups with '@' prefix) over all directories
+	Warning string            `yaml:"warning"` // quota usage raising a warning (default 90%), critical at 100%
+}
+
+// Validate checks the owner settings
+func (c Config) Validate() error {
+	for name, quota := range c.Quotas {
+		limit, err := human.ParseSize(quota)
+		if err != nil {
+			return fmt.Errorf("owners: invalid quota %q of %s: %w", quota, name, err)
+		}
+		if limit <= 0 {
+			return fmt.Errorf("owners: quota of %s must be positive", name)
+		}
+		if strings.HasPrefix(name, "@") && !c.Groups {
+			return fmt.Errorf("owners: group quota %s requires 'groups: true'", name)
+		}
+	}
+	if c.Warning != "" {
+		if threshold, err := alerting.ParseThreshold(c.Warning, false); err != nil || !threshold.Percent {
+			return fmt.Errorf("owners: invalid warning %q, percent expected (eg. 90%%)", c.Warning)
+		}
+	}
+	return nil
+}
+
+// WarningPercent returns the quota usage percent raising a warning
+func (c Config) WarningPercent() float64 {
+	if threshold, err := alerting.ParseThreshold(c.Warning, false); err == nil && c.Warning != "" {
+		return threshold.Value
+	}
+	return 90
+}
+
+// QuotaUsage returns the percent of the quota used by the user (or '@' prefixed group) and the alert level of the usage.
+// Returns false if there is no quota
+func (c Config) QuotaUsage(name string, size int64) (float64, alerting.Level, bool) {
+	quota, ok := c.Quotas[name]
+	if !ok {
+		return 0, alerting.OK, false
+	}
+	limit, _ := human.ParseSize(quota)
+	if limit <= 0 {
+		return 0, alerting.OK, false // rejected by Validate
+	}
+	percent := float64(size) * 100 / float64(limit)
+	switch {
+	case percent >= 100:
+		return percent, alerting.Critical, true
+	case percent >= c.WarningPercent():
+		return percent, alerting.Warning, true
+	}
+	return percent, alerting.OK, true
+}
+
+// QuotaAlerts returns alerts of users and groups ('@' prefixed) using more than the warning percent of their quota.
+// Totals are the usages over all directories, the alerts of larger usages go first
+func (c Config) QuotaAlerts(totals map[string]store.UsageStat) []alerting.Alert {
+	var names []string
+	for name := range totals {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if totals[names[i]].Size != totals[names[j]].Size {
+			return totals[names[i]].Size > totals[names[j]].Size
+		}
+		return names[i] < names[j]
+	})
+	var alerts []alerting.Alert
+	for _, name := range names {
+		if percent, level, ok := c.QuotaUsage(name, totals[name].Size); ok && level != alerting.OK {
+			alerts = append(alerts, alerting.Alert{Level: level, Rule: "quota of " + name,
+				Message: fmt.Sprintf("%s uses %s of %s quota (%.0f%%)", name, human.Size(totals[name].Size), c.Quotas[name], percent)})
+		}
+	}
+	return alerts
+}
@@ -0,0 +1,136 @@
+// Package progress tracks the scan in flight and estimates the progress of a walk by the number of entries
+// known from the previous walk
+package progress
+
+import (
+	"fmt"
+	"space-monitor/libs/human"
+	"sync"
+	"time"
+)
+
+// Rate returns the walked entries per second
+func Rate(seen int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(seen) / elapsed.Seconds()
+}
+
+// ETA returns the percent done and the remaining time of the walk expecting the number of entries.
+// Returns false if it can't be estimated (nothing expected, nothing walked yet or more entries than expected)
+func ETA(seen, expected int, elapsed time.Duration) (int, time.Duration, bool) {
+	rate := Rate(seen, elapsed)
+	if expected <= 0 || seen >= expected || rate <= 0 {
+		return 0, 0, false
+	}
+	return seen * 100 / expected, time.Duration(float64(expected-seen) / rate * float64(time.Second)), true
+}
+
+// Scan counts entries walked by the scan in flight. It is safe for concurrent use
+type Scan struct {
+	Now       func() time.Time    // clock (time.Now if nil)
+	ShortPath func(string) string // shortens the paths of the line (eg. "~/"), unchanged if nil
+
+	mu         sync.Mutex
+	active     bool
+	walking    bool
+	dir        string
+	path       string // the last walked path
+	dirIndex   int
+	dirCount   int
+	files      int
+	dirs       int
+	bytes      int64
+	expected   int // entries of the directory in the previous snapshot (0 if unknown)
+	started    time.Time
+	dirStarted time.Time
+}
+
+func (s *Scan) now() time.Time {
+	if s.Now != nil {
+		return s.Now()
+	}
+	return time.Now()
+}
+
+func (s *Scan) shortPath(path string) string {
+	if s.ShortPath != nil {
+		return s.ShortPath(path)
+	}
+	return path
+}
+
+// Begin starts tracking the scan of dirCount directories
+func (s *Scan) Begin(dirCount int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active, s.dirIndex, s.dirCount = true, 0, dirCount
+	s.started = s.now()
+}
+
+// StartDir switches to the next scanned directory. Expected is the number of its entries known from the previous scan
+func (s *Scan) StartDir(dir string, expected int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.walking, s.dir, s.path, s.expected = true, dir, dir, expected
+	s.files, s.dirs, s.bytes = 0, 0, 0
+	s.dirIndex++
+	s.dirStarted = s.now()
+}
+
+// Add counts the walked entry
+func (s *Scan) Add(path string, isDir bool, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.path = path
+	if isDir {
+		s.dirs++
+	} else {
+		s.files++
+		s.bytes += size
+	}
+}
+
+// EndDir marks the end of the directory walk
+func (s *Scan) EndDir() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.walking = false
+}
+
+// Finish stops tracking the scan
+func (s *Scan) Finish() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.active, s.walking = false, false
+}
+
+// Walking returns true if a directory is being walked
+func (s *Scan) Walking() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.walking
+}
+
+// String returns the progress line of the scan in flight (empty if there is no tracked scan)
+func (s *Scan) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.active {
+		return ""
+	}
+	if !s.walking {
+		return fmt.Sprintf("scanning (%d/%d dirs done), %s", s.dirIndex, s.dirCount, s.now().Sub(s.started).Round(time.Second))
+	}
+	elapsed := s.now().Sub(s.dirStarted)
+	seen := s.files + s.dirs
+	line := fmt.Sprintf("scanning %s (%d/%d): %d files, %d dirs, %s", s.shortPath(s.dir), s.dirIndex, s.dirCount, s.files, s.dirs, human.Size(s.bytes))
+	if elapsed > time.Second {
+		line += fmt.Sprintf(", %.0f/s", Rate(seen, elapsed))
+	}
+	if percent, eta, ok := ETA(seen, s.expected, elapsed); ok {
+		line += fmt.Sprintf(", %d%% ETA %s", percent, eta.Round(time.Second))
+	}
+	return line + " " + s.shortPath(s.path)
+}
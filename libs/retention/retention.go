@@ -0,0 +1,134 @@
+// Package retention decides which snapshots are kept by the grandfather-father-son retention policy
+package retention
+
+import (
+	"fmt"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
+	"strings"
+	"time"
+)
+
+// Policy snapshot retention policy (grandfather-father-son)
+type Policy struct {
+	KeepLast      int    `yaml:"keep-last"`       // keep N latest snapshots (max-snapshots by default)
+	KeepHourly    int    `yaml:"keep-hourly"`     // keep the latest snapshot for each of N last hours
+	KeepDaily     int    `yaml:"keep-daily"`      // keep the latest snapshot for each of N last days
+	KeepWeekly    int    `yaml:"keep-weekly"`     // keep the latest snapshot for each of N last weeks
+	KeepMonthly   int    `yaml:"keep-monthly"`    // keep the latest snapshot for each of N last months
+	MaxAge        string `yaml:"max-age"`         // delete snapshots older than this (eg. "90d"), even if keep-* rules match them
+	MaxTotalBytes string `yaml:"max-total-bytes"` // delete oldest snapshots while the data dir is larger (eg. "10G")
+}
+
+// HasKeepRules returns true if any keep-* rule is set
+func (p Policy) HasKeepRules() bool {
+	return p.KeepLast != 0 || p.KeepHourly != 0 || p.KeepDaily != 0 || p.KeepWeekly != 0 || p.KeepMonthly != 0
+}
+
+// Decision is the result of retention policy evaluation for a single snapshot
+type Decision struct {
+	Snapshot store.Snapshot
+	Size     int64 // snapshot size in the store (evaluated for max-total-bytes only)
+	Keep     bool
+	Reasons  []string
+}
+
+// bucket groups snapshots by time period. The latest snapshot of each period is kept
+type bucket struct {
+	name   string
+	count  int
+	period func(t time.Time) string
+}
+
+// Evaluate applies the policy to the snapshots (older first) at the time now. Pinned (tagged) snapshots are always kept.
+// The size function returns the storage size of the snapshot (used by max-total-bytes). Decisions are newer first
+func Evaluate(policy Policy, snapshots []store.Snapshot, now time.Time, size func(store.Snapshot) (int64, error)) ([]Decision, error) {
+	var maxAge time.Duration
+	if policy.MaxAge != "" {
+		var err error
+		if maxAge, err = human.ParseDuration(policy.MaxAge); err != nil {
+			return nil, fmt.Errorf("retention max-age: %w", err)
+		}
+	}
+	var maxTotalBytes int64
+	if policy.MaxTotalBytes != "" {
+		var err error
+		if maxTotalBytes, err = human.ParseSize(policy.MaxTotalBytes); err != nil {
+			return nil, fmt.Errorf("retention max-total-bytes: %w", err)
+		}
+	}
+
+	decisions := make([]Decision, len(snapshots))
+	for i := range snapshots { // newer first
+		decisions[i].Snapshot = snapshots[len(snapshots)-1-i]
+	}
+
+	buckets := []bucket{
+		{"hourly", policy.KeepHourly, func(t time.Time) string { return t.Format("2006-01-02 15") }},
+		{"daily", policy.KeepDaily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{"weekly", policy.KeepWeekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-%02d", year, week)
+		}},
+		{"monthly", policy.KeepMonthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+	lastPeriods := make([]string, len(buckets))
+	kept := make([]int, len(buckets))
+
+	var keptLast int
+	for i := range decisions {
+		d := &decisions[i]
+		if len(d.Snapshot.Tags) > 0 {
+			d.Keep = true
+			d.Reasons = append(d.Reasons, "pinned ("+strings.Join(d.Snapshot.Tags, ",")+")")
+			continue // pinned snapshots are never deleted and don't occupy keep-* slots
+		}
+		if maxAge > 0 && now.Sub(d.Snapshot.StartTime) > maxAge {
+			d.Reasons = append(d.Reasons, "older than max-age "+policy.MaxAge)
+			continue // too old snapshots don't occupy keep-* slots either
+		}
+		if keptLast < policy.KeepLast {
+			keptLast++
+			d.Keep = true
+			d.Reasons = append(d.Reasons, fmt.Sprintf("last %d", policy.KeepLast))
+		}
+		for b, bucket := range buckets {
+			period := bucket.period(d.Snapshot.StartTime)
+			if bucket.count == 0 || period == lastPeriods[b] {
+				continue
+			}
+			lastPeriods[b] = period
+			if kept[b] < bucket.count {
+				kept[b]++
+				d.Keep = true
+				d.Reasons = append(d.Reasons, bucket.name+" "+period)
+			}
+		}
+		if !d.Keep {
+			d.Reasons = append(d.Reasons, "not matched by keep rules")
+		}
+	}
+
+	if maxTotalBytes > 0 {
+		var total int64
+		for i := range decisions {
+			var err error
+			if decisions[i].Size, err = size(decisions[i].Snapshot); err != nil {
+				return nil, err
+			}
+			if decisions[i].Keep {
+				total += decisions[i].Size
+			}
+		}
+		for i := len(decisions) - 1; i >= 0 && total > maxTotalBytes; i-- { // remove older first
+			d := &decisions[i]
+			if !d.Keep || len(d.Snapshot.Tags) > 0 {
+				continue
+			}
+			total -= d.Size
+			d.Keep = false
+			d.Reasons = []string{"data dir exceeds max-total-bytes " + policy.MaxTotalBytes}
+		}
+	}
+	return decisions, nil
+}
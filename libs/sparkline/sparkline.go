@@ -0,0 +1,29 @@
+// Package sparkline renders series of numbers as unicode block sparklines
+package sparkline
+
+var ticks = []rune("▁▂▃▄▅▆▇█")
+
+// Render returns sparkline of the values (one rune per value). Constant series is rendered with the lowest tick
+func Render(values []int64) string {
+	if len(values) == 0 {
+		return ""
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		if v < min {
+			min = v
+		}
+		if v > max {
+			max = v
+		}
+	}
+	result := make([]rune, len(values))
+	for i, v := range values {
+		index := 0
+		if max > min {
+			index = int(float64(v-min) / float64(max-min) * float64(len(ticks)-1))
+		}
+		result[i] = ticks[index]
+	}
+	return string(result)
+}
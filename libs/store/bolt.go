@@ -0,0 +1,252 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go.etcd.io/bbolt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"strconv"
+	"time"
+)
+
+// BoltStore keeps the whole snapshot history in a single embedded bbolt database file.
+// Snapshot ids are sortable timestamps, so listing and time range queries are cursor seeks,
+// and per-path history is a prefix scan of the path hash
+type BoltStore struct {
+	db          *bbolt.DB
+	dataDir     string
+	compression string // file map compression
+	fileMapExt  string
+}
+
+var _ SnapshotStore = (*BoltStore)(nil) // BoltStore implements SnapshotStore interface
+
+const BoltFormatVersion = 1
+
+var (
+	boltBucketMeta         = []byte("meta")
+	boltBucketSnapshots    = []byte("snapshots")     // snapshot id -> snapshot (yaml)
+	boltBucketDirInfo      = []byte("dirinfo")       // path hash + "/" + snapshot id -> dir info (yaml)
+	boltBucketFileMaps     = []byte("filemaps")      // path hash + "/" + snapshot id -> file map extension + "\n" + encoded file map
+	boltBucketSnapshotDirs = []byte("snapshot-dirs") // snapshot id + "/" + path hash -> nothing (index for deletion)
+	boltKeyFormatVersion   = []byte("format-version")
+)
+
+// OpenBoltStore opens (or creates) the bolt database file. Report files are kept in the data dir
+func OpenBoltStore(file string, dataDir string, compression string) (*BoltStore, error) {
+	ext, err := FileMapExt(compression)
+	if err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(file, 0666, &bbolt.Options{Timeout: 10 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", file, err)
+	}
+	err = db.Update(func(tx *bbolt.Tx) error {
+		for _, name := range [][]byte{boltBucketMeta, boltBucketSnapshots, boltBucketDirInfo, boltBucketFileMaps, boltBucketSnapshotDirs} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		meta := tx.Bucket(boltBucketMeta)
+		if value := meta.Get(boltKeyFormatVersion); value != nil {
+			version, _ := strconv.Atoi(string(value))
+			if version > BoltFormatVersion {
+				return fmt.Errorf("%s has format version %d, newer than supported %d. Please upgrade space-monitor", file, version, BoltFormatVersion)
+			}
+			return nil
+		}
+		return meta.Put(boltKeyFormatVersion, []byte(strconv.Itoa(BoltFormatVersion)))
+	})
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return &BoltStore{db: db, dataDir: dataDir, compression: compression, fileMapExt: ext}, nil
+}
+
+func boltDirKey(path string, id string) []byte {
+	return []byte(PathHash(path) + "/" + id)
+}
+
+func (s *BoltStore) Save(snapshot Snapshot) error {
+	if snapshot.ID == "" {
+		return errors.New("snapshot id is not set")
+	}
+	value, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		return tx.Bucket(boltBucketSnapshots).Put([]byte(snapshot.ID), value)
+	})
+}
+
+func (s *BoltStore) SaveDirInfo(snapshot Snapshot, info DirInfo) error {
+	value, err := yaml.Marshal(info)
+	if err != nil {
+		return err
+	}
+	var fileMapValue []byte
+	if info.FileMap != nil {
+		var buffer bytes.Buffer
+		buffer.WriteString(s.fileMapExt + "\n")
+		if _, err := EncodeFileMap(&buffer, info.FileMap, s.compression); err != nil {
+			return err
+		}
+		fileMapValue = buffer.Bytes()
+	}
+	key := boltDirKey(info.Path, snapshot.ID)
+	return s.db.Update(func(tx *bbolt.Tx) error {
+		if err := tx.Bucket(boltBucketDirInfo).Put(key, value); err != nil {
+			return err
+		}
+		if fileMapValue != nil {
+			if err := tx.Bucket(boltBucketFileMaps).Put(key, fileMapValue); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltBucketSnapshotDirs).Put([]byte(snapshot.ID+"/"+PathHash(info.Path)), nil)
+	})
+}
+
+func (s *BoltStore) Load(id string) (Snapshot, error) {
+	var snap Snapshot
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		value := tx.Bucket(boltBucketSnapshots).Get([]byte(id))
+		if value == nil {
+			return fmt.Errorf("snapshot %q not found", id)
+		}
+		return yaml.Unmarshal(value, &snap)
+	})
+	snap.ID = id
+	return snap, err
+}
+
+func (s *BoltStore) List() ([]Snapshot, error) {
+	return s.ListRange(time.Time{}, time.Now().AddDate(100, 0, 0))
+}
+
+func (s *BoltStore) ListRange(from, to time.Time) ([]Snapshot, error) {
+	var list []Snapshot
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		cursor := tx.Bucket(boltBucketSnapshots).Cursor()
+		last := []byte(NewSnapshotID(to))
+		for key, value := cursor.Seek([]byte(NewSnapshotID(from))); key != nil && bytes.Compare(key, last) <= 0; key, value = cursor.Next() {
+			var snap Snapshot
+			if err := yaml.Unmarshal(value, &snap); err != nil {
+				return err
+			}
+			snap.ID = string(key)
+			list = append(list, snap)
+		}
+		return nil
+	})
+	return filterRange(list, from, to), err
+}
+
+func (s *BoltStore) Delete(snapshot Snapshot) error {
+	if snapshot.ID == "" {
+		return errors.New("snapshot id is not set")
+	}
+	err := s.db.Update(func(tx *bbolt.Tx) error {
+		prefix := []byte(snapshot.ID + "/")
+		index := tx.Bucket(boltBucketSnapshotDirs)
+		var indexKeys [][]byte
+		cursor := index.Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			indexKeys = append(indexKeys, append([]byte{}, key...))
+		}
+		for _, indexKey := range indexKeys {
+			dirKey := []byte(string(indexKey[len(prefix):]) + "/" + snapshot.ID)
+			if err := tx.Bucket(boltBucketDirInfo).Delete(dirKey); err != nil {
+				return err
+			}
+			if err := tx.Bucket(boltBucketFileMaps).Delete(dirKey); err != nil {
+				return err
+			}
+			if err := index.Delete(indexKey); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket(boltBucketSnapshots).Delete([]byte(snapshot.ID))
+	})
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(s.ReportDir(snapshot))
+}
+
+func (s *BoltStore) LoadDirInfo(snapshot Snapshot, path string, detailed bool) (DirInfo, error) {
+	var info DirInfo
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		key := boltDirKey(path, snapshot.ID)
+		value := tx.Bucket(boltBucketDirInfo).Get(key)
+		if value == nil {
+			return fmt.Errorf("no dir info for %s in snapshot %s", path, snapshot.ID)
+		}
+		if err := yaml.Unmarshal(value, &info); err != nil {
+			return err
+		}
+		if !detailed {
+			return nil
+		}
+		value = tx.Bucket(boltBucketFileMaps).Get(key)
+		if value == nil {
+			return fmt.Errorf("no file map for %s in snapshot %s", path, snapshot.ID)
+		}
+		ext, payload, found := bytes.Cut(value, []byte("\n"))
+		if !found {
+			return fmt.Errorf("corrupted file map of %s in snapshot %s", path, snapshot.ID)
+		}
+		var err error
+		info.FileMap, err = ReadFileMapFormat(bytes.NewReader(payload), string(ext))
+		return err
+	})
+	info.SnapshotID = snapshot.ID
+	return info, err
+}
+
+func (s *BoltStore) History(path string) ([]DirInfo, error) {
+	var list []DirInfo
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		prefix := []byte(PathHash(path) + "/")
+		cursor := tx.Bucket(boltBucketDirInfo).Cursor()
+		for key, value := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, value = cursor.Next() {
+			var info DirInfo
+			if err := yaml.Unmarshal(value, &info); err != nil {
+				return err
+			}
+			info.SnapshotID = string(key[len(prefix):])
+			list = append(list, info)
+		}
+		return nil
+	})
+	return list, err
+}
+
+func (s *BoltStore) Size(snapshot Snapshot) (int64, error) {
+	size := DirSize(s.ReportDir(snapshot))
+	err := s.db.View(func(tx *bbolt.Tx) error {
+		size += int64(len(tx.Bucket(boltBucketSnapshots).Get([]byte(snapshot.ID))))
+		prefix := []byte(snapshot.ID + "/")
+		cursor := tx.Bucket(boltBucketSnapshotDirs).Cursor()
+		for key, _ := cursor.Seek(prefix); key != nil && bytes.HasPrefix(key, prefix); key, _ = cursor.Next() {
+			dirKey := []byte(string(key[len(prefix):]) + "/" + snapshot.ID)
+			size += int64(len(tx.Bucket(boltBucketDirInfo).Get(dirKey)))
+			size += int64(len(tx.Bucket(boltBucketFileMaps).Get(dirKey)))
+		}
+		return nil
+	})
+	return size, err
+}
+
+func (s *BoltStore) ReportDir(snapshot Snapshot) string {
+	return s.dataDir + "/reports/" + snapshot.ID
+}
+
+func (s *BoltStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,62 @@
+package store
+
+import "sort"
+
+// CopyStore copies snapshots of the directory paths (with file maps) from the source store to the destination one.
+// Snapshots already present in the destination are skipped. Returns ids of the copied snapshots
+func CopyStore(dst, src SnapshotStore, paths []string) ([]string, error) {
+	snapshots, err := src.List()
+	if err != nil {
+		return nil, err
+	}
+	existing, err := dst.List()
+	if err != nil {
+		return nil, err
+	}
+	skip := map[string]bool{}
+	for _, snap := range existing {
+		skip[snap.ID] = true
+	}
+	byID := map[string]Snapshot{}
+	for _, snap := range snapshots {
+		if !skip[snap.ID] {
+			byID[snap.ID] = snap
+		}
+	}
+
+	// dir infos of every snapshot, found by the per-path history
+	dirs := map[string][]string{} // snapshot id -> paths
+	for _, path := range paths {
+		history, err := src.History(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, info := range history {
+			dirs[info.SnapshotID] = append(dirs[info.SnapshotID], path)
+		}
+	}
+
+	var copied []string
+	for id := range byID {
+		copied = append(copied, id)
+	}
+	sort.Strings(copied)
+	for _, id := range copied {
+		snap := byID[id]
+		if err := dst.Save(snap); err != nil {
+			return nil, err
+		}
+		for _, path := range dirs[id] {
+			info, err := src.LoadDirInfo(snap, path, true)
+			if err != nil { // no file map (detailed mode was off)
+				if info, err = src.LoadDirInfo(snap, path, false); err != nil {
+					return nil, err
+				}
+			}
+			if err := dst.SaveDirInfo(snap, info); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return copied, nil
+}
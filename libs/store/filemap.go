@@ -0,0 +1,211 @@
+package store
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"sort"
+)
+
+// File map storage formats. The format is defined by the file extension
+const (
+	FileMapExtLegacy = ".gob"      // raw gob-encoded map with absolute paths as keys
+	FileMapExtPlain  = ".fmap"     // prefix-encoded entries, not compressed
+	FileMapExtGzip   = ".fmap.gz"  // prefix-encoded entries, gzip compressed
+	FileMapExtZstd   = ".fmap.zst" // prefix-encoded entries, zstd compressed
+)
+
+// maximal number of file map entries allocated before they are read
+const maxFileMapPreallocation = 64 * 1024
+
+// fileMapHeader is the first record of the prefix-encoded file map stream
+type fileMapHeader struct {
+	Count int // number of entries that follow
+}
+
+// fileMapEntry is a single prefix-encoded file map record. Paths are sorted,
+// so every path shares a (usually long) prefix with the previous one
+type fileMapEntry struct {
+	Prefix int    // length of the prefix shared with the previous path
+	Suffix string // the rest of the path
+	Info   FileInfo
+}
+
+// FileMapExt returns file map file extension for the compression method (gzip, zstd or none)
+func FileMapExt(compression string) (string, error) {
+	switch compression {
+	case "", "gzip":
+		return FileMapExtGzip, nil
+	case "zstd":
+		return FileMapExtZstd, nil
+	case "none":
+		return FileMapExtPlain, nil
+	}
+	return "", fmt.Errorf("unknown compression: %q (gzip, zstd or none expected)", compression)
+}
+
+// SaveFileMap writes file map to basePath + extension of the compression method
+func SaveFileMap(basePath string, fileMap map[string]FileInfo, compression string) error {
+	ext, err := FileMapExt(compression)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(basePath + ext)
+	if err != nil {
+		return err
+	}
+	// noinspection GoUnhandledErrorResult
+	defer file.Close()
+
+	buffered := bufio.NewWriter(file)
+	if _, err := EncodeFileMap(buffered, fileMap, compression); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// EncodeFileMap writes prefix-encoded file map compressed with the compression method.
+// Returns file map extension (format) of the written data
+func EncodeFileMap(w io.Writer, fileMap map[string]FileInfo, compression string) (string, error) {
+	ext, err := FileMapExt(compression)
+	if err != nil {
+		return "", err
+	}
+	var writer io.WriteCloser
+	switch ext {
+	case FileMapExtGzip:
+		writer = gzip.NewWriter(w)
+	case FileMapExtZstd:
+		if writer, err = zstd.NewWriter(w); err != nil {
+			return "", err
+		}
+	default:
+		writer = nopWriteCloser{w}
+	}
+	if err := WriteFileMap(writer, fileMap); err != nil {
+		return "", err
+	}
+	return ext, writer.Close()
+}
+
+// LoadFileMap reads file map stored at basePath in any known format (including the legacy .gob one)
+func LoadFileMap(basePath string) (map[string]FileInfo, error) {
+	for _, ext := range []string{FileMapExtZstd, FileMapExtGzip, FileMapExtPlain, FileMapExtLegacy} {
+		file, err := os.Open(basePath + ext)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		// noinspection GoUnhandledErrorResult
+		defer file.Close()
+		return ReadFileMapFormat(bufio.NewReader(file), ext)
+	}
+	return nil, fmt.Errorf("no file map found for %s", basePath)
+}
+
+// ReadFileMapFormat decodes file map of the format (file extension) from the reader
+func ReadFileMapFormat(reader io.Reader, ext string) (map[string]FileInfo, error) {
+	switch ext {
+	case FileMapExtLegacy:
+		var fileMap map[string]FileInfo
+		err := gob.NewDecoder(reader).Decode(&fileMap)
+		return fileMap, err
+	case FileMapExtGzip:
+		gzipReader, err := gzip.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		// noinspection GoUnhandledErrorResult
+		defer gzipReader.Close()
+		return ReadFileMap(gzipReader)
+	case FileMapExtZstd:
+		zstdReader, err := zstd.NewReader(reader)
+		if err != nil {
+			return nil, err
+		}
+		defer zstdReader.Close()
+		return ReadFileMap(zstdReader)
+	case FileMapExtPlain:
+		return ReadFileMap(reader)
+	}
+	return nil, fmt.Errorf("unknown file map format: %q", ext)
+}
+
+// WriteFileMap writes prefix-encoded file map (without compression)
+func WriteFileMap(writer io.Writer, fileMap map[string]FileInfo) error {
+	paths := make([]string, 0, len(fileMap))
+	for path := range fileMap {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	encoder := gob.NewEncoder(writer)
+	if err := encoder.Encode(fileMapHeader{Count: len(paths)}); err != nil {
+		return err
+	}
+	prev := ""
+	for _, path := range paths {
+		prefix := commonPrefixLen(prev, path)
+		if err := encoder.Encode(fileMapEntry{Prefix: prefix, Suffix: path[prefix:], Info: fileMap[path]}); err != nil {
+			return err
+		}
+		prev = path
+	}
+	return nil
+}
+
+// ReadFileMap reads prefix-encoded file map (without compression)
+func ReadFileMap(reader io.Reader) (map[string]FileInfo, error) {
+	decoder := gob.NewDecoder(reader)
+	var header fileMapHeader
+	if err := decoder.Decode(&header); err != nil {
+		return nil, err
+	}
+	if header.Count < 0 {
+		return nil, fmt.Errorf("corrupted file map header: %d entries", header.Count)
+	}
+	// the count comes from disk: a corrupted one must not allocate huge memory before decoding fails
+	preallocate := header.Count
+	if preallocate > maxFileMapPreallocation {
+		preallocate = maxFileMapPreallocation
+	}
+	fileMap := make(map[string]FileInfo, preallocate)
+	prev := ""
+	for i := 0; i < header.Count; i++ {
+		var entry fileMapEntry
+		if err := decoder.Decode(&entry); err != nil {
+			return nil, err
+		}
+		if entry.Prefix > len(prev) {
+			return nil, fmt.Errorf("corrupted file map entry #%d", i)
+		}
+		path := prev[:entry.Prefix] + entry.Suffix
+		fileMap[path] = entry.Info
+		prev = path
+	}
+	return fileMap, nil
+}
+
+func commonPrefixLen(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return n
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FolderStore keeps every snapshot in its own timestamped folder of the data dir:
+// snapshot.dat, manifest.yaml, dirinfo-<path hash>.dat and dirinfo-<path hash>.fmap.* files
+type FolderStore struct {
+	dataDir     string
+	compression string // file map compression
+	fileMapExt  string
+	mutex       sync.Mutex
+	manifests   map[string]Manifest // manifests by snapshot id, read on the first file access
+}
+
+var _ SnapshotStore = (*FolderStore)(nil) // FolderStore implements SnapshotStore interface
+
+// NewFolderStore returns the folder store of the data directory. File maps are saved with the compression (gzip, zstd or none)
+func NewFolderStore(dataDir string, compression string) (*FolderStore, error) {
+	ext, err := FileMapExt(compression)
+	if err != nil {
+		return nil, err
+	}
+	return &FolderStore{dataDir: dataDir, compression: compression, fileMapExt: ext, manifests: map[string]Manifest{}}, nil
+}
+
+// Dir returns the snapshot folder
+func (s *FolderStore) Dir(snapshot Snapshot) string {
+	return s.dataDir + "/" + snapshot.ID
+}
+
+func (s *FolderStore) Save(snapshot Snapshot) error {
+	if snapshot.ID == "" {
+		return errors.New("snapshot id is not set")
+	}
+	if err := os.MkdirAll(s.Dir(snapshot), 0777); err != nil {
+		return err
+	}
+	bytes, err := yaml.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.Dir(snapshot)+"/snapshot.dat", bytes, 0666); err != nil {
+		return err
+	}
+	// a new folder gets the manifest right away, so the snapshot being written is never taken for a legacy one
+	if _, err := os.Stat(s.Dir(snapshot) + "/" + ManifestFileName); errors.Is(err, os.ErrNotExist) {
+		if datFiles, _ := filepath.Glob(s.Dir(snapshot) + "/dirinfo-*.dat"); len(datFiles) == 0 {
+			return SaveManifest(s.Dir(snapshot), nil, s.fileMapExt)
+		}
+	}
+	return nil
+}
+
+func (s *FolderStore) SaveDirInfo(snapshot Snapshot, info DirInfo) error {
+	datFile := fmt.Sprintf(s.Dir(snapshot)+"/dirinfo-%s.dat", PathHash(info.Path))
+	if info.FileMap != nil {
+		if err := SaveFileMap(strings.TrimSuffix(datFile, ".dat"), info.FileMap, s.compression); err != nil {
+			return err
+		}
+	}
+	bytes, err := yaml.Marshal(info)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(datFile, bytes, 0666); err != nil {
+		return err
+	}
+	s.forgetManifest(snapshot.ID)
+	return AddToManifest(s.Dir(snapshot), info, s.fileMapExt)
+}
+
+func (s *FolderStore) Load(id string) (Snapshot, error) {
+	return LoadSnapshotFile(s.dataDir + "/" + id + "/snapshot.dat")
+}
+
+// List returns all readable snapshots. Unreadable ones are skipped and reported by the error (the list is still valid)
+func (s *FolderStore) List() ([]Snapshot, error) {
+	files, err := filepath.Glob(s.dataDir + "/*/snapshot.dat")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var list []Snapshot
+	var skipped []string
+	for _, file := range files {
+		snap, err := LoadSnapshotFile(file)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		list = append(list, snap)
+	}
+	if skipped != nil {
+		return list, &SkippedError{Skipped: skipped}
+	}
+	return list, nil
+}
+
+func (s *FolderStore) ListRange(from, to time.Time) ([]Snapshot, error) {
+	list, err := s.List()
+	return filterRange(list, from, to), err
+}
+
+func (s *FolderStore) Delete(snapshot Snapshot) error {
+	if snapshot.ID == "" {
+		return errors.New("snapshot id is not set")
+	}
+	s.forgetManifest(snapshot.ID)
+	return os.RemoveAll(s.Dir(snapshot))
+}
+
+func (s *FolderStore) LoadDirInfo(snapshot Snapshot, path string, detailed bool) (DirInfo, error) {
+	manifest, err := s.manifest(snapshot)
+	if err != nil {
+		return DirInfo{}, err
+	}
+	info, err := loadDirInfoFile(fmt.Sprintf(s.Dir(snapshot)+"/dirinfo-%s.dat", PathHash(path)), manifest, detailed)
+	info.SnapshotID = snapshot.ID
+	return info, err
+}
+
+// manifest returns the (cached) manifest of the snapshot folder
+func (s *FolderStore) manifest(snapshot Snapshot) (Manifest, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if manifest, ok := s.manifests[snapshot.ID]; ok {
+		return manifest, nil
+	}
+	manifest, err := ReadManifest(s.Dir(snapshot))
+	if err == nil {
+		s.manifests[snapshot.ID] = manifest
+	}
+	return manifest, err
+}
+
+func (s *FolderStore) forgetManifest(id string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.manifests, id)
+}
+
+func (s *FolderStore) History(path string) ([]DirInfo, error) {
+	files, err := filepath.Glob(s.dataDir + fmt.Sprintf("/*/dirinfo-%s.dat", PathHash(path)))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var list []DirInfo
+	var skipped []string
+	for _, file := range files {
+		info, err := ReadDirInfoDat(file)
+		if err != nil {
+			skipped = append(skipped, err.Error())
+			continue
+		}
+		info.SnapshotID = filepath.Base(filepath.Dir(file))
+		list = append(list, info)
+	}
+	if skipped != nil {
+		return list, &SkippedError{Skipped: skipped}
+	}
+	return list, nil
+}
+
+func (s *FolderStore) Size(snapshot Snapshot) (int64, error) {
+	return DirSize(s.Dir(snapshot)), nil
+}
+
+func (s *FolderStore) ReportDir(snapshot Snapshot) string {
+	return s.Dir(snapshot)
+}
+
+func (s *FolderStore) Close() error {
+	return nil
+}
+
+// LoadSnapshotFile loads snapshot struct from the snapshot.dat file. The format version is checked
+// only when the directory infos are loaded
+func LoadSnapshotFile(file string) (Snapshot, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	snap := Snapshot{}
+	if err := yaml.Unmarshal(bytes, &snap); err != nil {
+		return snap, err
+	}
+	snap.ID = filepath.Base(filepath.Dir(file))
+	return snap, nil
+}
+
+// LoadDirInfoFile loads dir info struct from .dat file and (optionally) its file map, according to the snapshot format version
+func LoadDirInfoFile(datFile string, detailed bool) (DirInfo, error) {
+	manifest, err := ReadManifest(filepath.Dir(datFile))
+	if err != nil {
+		return DirInfo{}, err
+	}
+	return loadDirInfoFile(datFile, manifest, detailed)
+}
+
+func loadDirInfoFile(datFile string, manifest Manifest, detailed bool) (DirInfo, error) {
+	info, err := ReadDirInfoDat(datFile)
+	if err != nil {
+		return info, err
+	}
+
+	if detailed {
+		info.FileMap, err = manifest.LoadFileMap(datFile)
+		if err != nil {
+			return info, err
+		}
+	}
+
+	return info, nil
+}
+
+// ReadDirInfoDat reads dir info struct from the .dat (yaml) file
+func ReadDirInfoDat(datFile string) (DirInfo, error) {
+	bytes, err := os.ReadFile(datFile)
+	if err != nil {
+		return DirInfo{}, err
+	}
+	info := DirInfo{}
+	err = yaml.Unmarshal(bytes, &info)
+	return info, err
+}
@@ -0,0 +1,265 @@
+package store
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v2"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snapshot directory format versions
+const (
+	FormatVersionLegacy     = 1 // snapshot.dat, dirinfo-*.dat and raw .gob file maps, no manifest
+	FormatVersionCompressed = 2 // prefix-encoded compressed .fmap.* file maps, no manifest
+	FormatVersionManifest   = 3 // manifest.yaml with format version and list of files
+
+	CurrentFormatVersion = FormatVersionManifest
+	ManifestFileName     = "manifest.yaml"
+)
+
+// Manifest describes the content of a snapshot directory
+type Manifest struct {
+	FormatVersion int           `yaml:"format-version"`
+	Created       time.Time     `yaml:"created"`
+	Dirs          []ManifestDir `yaml:"dirs"`
+}
+
+// ManifestDir describes files of a single scanned directory
+type ManifestDir struct {
+	Path    string `yaml:"path"`
+	Info    string `yaml:"info"`               // dir info (.dat) file name
+	FileMap string `yaml:"file-map,omitempty"` // file map file name (detailed mode only)
+}
+
+// ReadManifest reads manifest of the snapshot directory. Manifest of older
+// (manifest-less) formats is reconstructed from the directory content
+func ReadManifest(snapshotDir string) (Manifest, error) {
+	bytes, err := os.ReadFile(snapshotDir + "/" + ManifestFileName)
+	if errors.Is(err, os.ErrNotExist) {
+		return detectLegacyManifest(snapshotDir), nil
+	}
+	if err != nil {
+		return Manifest{}, err
+	}
+	var manifest Manifest
+	if err := yaml.Unmarshal(bytes, &manifest); err != nil {
+		return Manifest{}, err
+	}
+	if manifest.FormatVersion > CurrentFormatVersion {
+		return manifest, fmt.Errorf("snapshot %s has format version %d, newer than supported %d. Please upgrade space-monitor",
+			filepath.Base(snapshotDir), manifest.FormatVersion, CurrentFormatVersion)
+	}
+	return manifest, nil
+}
+
+// detectLegacyManifest builds manifest for snapshot directories written before manifests were introduced.
+// Any .gob file map makes it v1. Without file maps v1 and v2 are identical, such folders are reported as v2
+func detectLegacyManifest(snapshotDir string) Manifest {
+	manifest := Manifest{FormatVersion: FormatVersionCompressed}
+	datFiles, _ := filepath.Glob(snapshotDir + "/dirinfo-*.dat")
+	for _, datFile := range datFiles {
+		base := strings.TrimSuffix(datFile, ".dat")
+		dir := ManifestDir{Info: filepath.Base(datFile)}
+		for _, ext := range []string{FileMapExtZstd, FileMapExtGzip, FileMapExtPlain, FileMapExtLegacy} {
+			if _, err := os.Stat(base + ext); err == nil {
+				dir.FileMap = filepath.Base(base + ext)
+				if ext == FileMapExtLegacy {
+					manifest.FormatVersion = FormatVersionLegacy
+				}
+				break
+			}
+		}
+		if info, err := ReadDirInfoDat(datFile); err == nil {
+			dir.Path = info.Path
+		}
+		manifest.Dirs = append(manifest.Dirs, dir)
+	}
+	return manifest
+}
+
+// FindDir returns manifest entry of the dir info file
+func (m Manifest) FindDir(infoFileName string) (ManifestDir, bool) {
+	for _, dir := range m.Dirs {
+		if dir.Info == infoFileName {
+			return dir, true
+		}
+	}
+	return ManifestDir{}, false
+}
+
+// LoadFileMap loads file map of the dir info file using the reader of the snapshot format version
+func (m Manifest) LoadFileMap(datFile string) (map[string]FileInfo, error) {
+	if m.FormatVersion < FormatVersionManifest {
+		return LoadFileMap(strings.TrimSuffix(datFile, ".dat")) // probe all known file map formats
+	}
+	dir, ok := m.FindDir(filepath.Base(datFile))
+	if !ok || dir.FileMap == "" {
+		return nil, fmt.Errorf("no file map for %s in the manifest", filepath.Base(datFile))
+	}
+	file, err := os.Open(filepath.Dir(datFile) + "/" + dir.FileMap)
+	if err != nil {
+		return nil, err
+	}
+	// noinspection GoUnhandledErrorResult
+	defer file.Close()
+	return ReadFileMapFormat(bufio.NewReader(file), strings.TrimPrefix(dir.FileMap, strings.TrimSuffix(dir.Info, ".dat")))
+}
+
+// SaveManifest writes manifest of the current format for the snapshot directory
+func SaveManifest(snapshotDir string, infoList []DirInfo, fileMapExt string) error {
+	manifest := Manifest{FormatVersion: CurrentFormatVersion, Created: time.Now()}
+	for _, info := range infoList {
+		manifest.Dirs = append(manifest.Dirs, newManifestDir(info, fileMapExt))
+	}
+	return writeManifest(snapshotDir, manifest)
+}
+
+func newManifestDir(info DirInfo, fileMapExt string) ManifestDir {
+	dir := ManifestDir{Path: info.Path, Info: fmt.Sprintf("dirinfo-%s.dat", PathHash(info.Path))}
+	if info.FileMap != nil {
+		dir.FileMap = strings.TrimSuffix(dir.Info, ".dat") + fileMapExt
+	}
+	return dir
+}
+
+func writeManifest(snapshotDir string, manifest Manifest) error {
+	bytes, err := yaml.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(snapshotDir+"/"+ManifestFileName, bytes, 0666)
+}
+
+// AddToManifest adds (or replaces) the directory entry to the manifest of the snapshot directory
+func AddToManifest(snapshotDir string, info DirInfo, fileMapExt string) error {
+	manifest := Manifest{FormatVersion: CurrentFormatVersion, Created: time.Now()}
+	bytes, err := os.ReadFile(snapshotDir + "/" + ManifestFileName)
+	if err == nil {
+		err = yaml.Unmarshal(bytes, &manifest)
+	}
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	entry := newManifestDir(info, fileMapExt)
+	for i, dir := range manifest.Dirs {
+		if dir.Info == entry.Info {
+			manifest.Dirs = append(manifest.Dirs[:i], manifest.Dirs[i+1:]...)
+			break
+		}
+	}
+	manifest.Dirs = append(manifest.Dirs, entry)
+	return writeManifest(snapshotDir, manifest)
+}
+
+// MigrateSnapshot upgrades the snapshot directory to the current format
+func MigrateSnapshot(snapshotDir string, compression string) error {
+	ext, err := FileMapExt(compression)
+	if err != nil {
+		return err
+	}
+	manifest, err := ReadManifest(snapshotDir)
+	if err != nil {
+		return err
+	}
+	var infoList []DirInfo
+	for _, dir := range manifest.Dirs {
+		datFile := snapshotDir + "/" + dir.Info
+		info, err := LoadDirInfoFile(datFile, false)
+		if err != nil {
+			return err
+		}
+		if dir.FileMap != "" {
+			if info.FileMap, err = manifest.LoadFileMap(datFile); err != nil {
+				return err
+			}
+			base := strings.TrimSuffix(datFile, ".dat")
+			if err := SaveFileMap(base, info.FileMap, compression); err != nil {
+				return err
+			}
+			if old := snapshotDir + "/" + dir.FileMap; old != base+ext {
+				if err := os.Remove(old); err != nil {
+					return err
+				}
+			}
+		}
+		infoList = append(infoList, info)
+	}
+	return SaveManifest(snapshotDir, infoList, ext)
+}
+
+// OutdatedSnapshot is a snapshot folder of a format older than the current one
+type OutdatedSnapshot struct {
+	Dir           string
+	FormatVersion int
+}
+
+// FindOutdated returns snapshot folders of the data dir having a format older than the current one.
+// Folders without snapshot.dat (eg. reports of the bolt storage) are not snapshots and are ignored
+func FindOutdated(dataDir string) ([]OutdatedSnapshot, error) {
+	files, err := filepath.Glob(dataDir + "/*/snapshot.dat")
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+	var outdated []OutdatedSnapshot
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		manifest, err := ReadManifest(dir)
+		if err != nil {
+			return nil, err
+		}
+		if manifest.FormatVersion < CurrentFormatVersion {
+			outdated = append(outdated, OutdatedSnapshot{Dir: dir, FormatVersion: manifest.FormatVersion})
+		}
+	}
+	return outdated, nil
+}
+
+// MigrateDataDir copies the data dir to the backup dir and upgrades the outdated snapshot folders to the current format.
+// The backup is kept, so a failed migration can be rolled back by hand
+func MigrateDataDir(dataDir, backupDir string, outdated []OutdatedSnapshot, compression string) error {
+	if err := CopyDir(dataDir, backupDir); err != nil {
+		return fmt.Errorf("backup failed: %w", err)
+	}
+	for _, snapshot := range outdated {
+		if err := MigrateSnapshot(snapshot.Dir, compression); err != nil {
+			return fmt.Errorf("migration of %s failed: %w", filepath.Base(snapshot.Dir), err)
+		}
+	}
+	return nil
+}
+
+// CopyDir recursively copies the directory
+func CopyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, strings.TrimPrefix(path, src))
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0777)
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		// noinspection GoUnhandledErrorResult
+		defer in.Close()
+		out, err := os.Create(target)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(out, in); err != nil {
+			_ = out.Close()
+			return err
+		}
+		return out.Close()
+	})
+}
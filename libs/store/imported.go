@@ -0,0 +1,43 @@
+package store
+
+import "path/filepath"
+
+// NewImportedDirInfo returns directory info of the file map having sizes of files only (eg. imported du output).
+// Missing parent directories are added, directory sizes are aggregated like the scan does
+func NewImportedDirInfo(root string, fileMap map[string]FileInfo) DirInfo {
+	info := DirInfo{Path: root, FileMap: map[string]FileInfo{root: {IsDir: true}}}
+	for path, file := range fileMap {
+		if !file.IsDir {
+			continue
+		}
+		for current := path; IsSubPath(current, root); current = filepath.Dir(current) {
+			info.FileMap[current] = FileInfo{IsDir: true}
+			if current == root {
+				break
+			}
+		}
+	}
+	for path, file := range fileMap {
+		if file.IsDir || !IsSubPath(path, root) || path == root {
+			continue
+		}
+		info.FileMap[path] = FileInfo{Size: file.Size}
+		info.Files++
+		info.Size += file.Size
+		for current := filepath.Dir(path); IsSubPath(current, root); current = filepath.Dir(current) {
+			parent := info.FileMap[current]
+			parent.IsDir = true
+			parent.Size += file.Size
+			info.FileMap[current] = parent
+			if current == root {
+				break
+			}
+		}
+	}
+	for _, file := range info.FileMap {
+		if file.IsDir {
+			info.Dirs++
+		}
+	}
+	return info
+}
@@ -0,0 +1,145 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps snapshots in memory only. It is used for tests and runs that must not touch the data dir
+type MemoryStore struct {
+	mutex     sync.RWMutex
+	snapshots map[string]Snapshot
+	dirInfos  map[string]map[string]DirInfo // snapshot id -> path -> dir info
+}
+
+var _ SnapshotStore = (*MemoryStore)(nil) // MemoryStore implements SnapshotStore interface
+
+// NewMemoryStore returns an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		snapshots: map[string]Snapshot{},
+		dirInfos:  map[string]map[string]DirInfo{},
+	}
+}
+
+func (s *MemoryStore) Save(snapshot Snapshot) error {
+	if snapshot.ID == "" {
+		return errors.New("snapshot id is not set")
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	snapshot.InfoList = nil
+	snapshot.Tags = append([]string(nil), snapshot.Tags...)
+	s.snapshots[snapshot.ID] = snapshot
+	return nil
+}
+
+func (s *MemoryStore) SaveDirInfo(snapshot Snapshot, info DirInfo) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if _, ok := s.dirInfos[snapshot.ID]; !ok {
+		s.dirInfos[snapshot.ID] = map[string]DirInfo{}
+	}
+	info.FileMap = copyFileMap(info.FileMap)
+	s.dirInfos[snapshot.ID][info.Path] = info
+	return nil
+}
+
+func (s *MemoryStore) Load(id string) (Snapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	snap, ok := s.snapshots[id]
+	if !ok {
+		return Snapshot{}, fmt.Errorf("snapshot %q not found", id)
+	}
+	return snap, nil
+}
+
+func (s *MemoryStore) List() ([]Snapshot, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var list []Snapshot
+	for _, snap := range s.snapshots {
+		list = append(list, snap)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].ID < list[j].ID
+	})
+	return list, nil
+}
+
+func (s *MemoryStore) ListRange(from, to time.Time) ([]Snapshot, error) {
+	list, err := s.List()
+	return filterRange(list, from, to), err
+}
+
+func (s *MemoryStore) Delete(snapshot Snapshot) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.snapshots, snapshot.ID)
+	delete(s.dirInfos, snapshot.ID)
+	return nil
+}
+
+func (s *MemoryStore) LoadDirInfo(snapshot Snapshot, path string, detailed bool) (DirInfo, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	info, ok := s.dirInfos[snapshot.ID][path]
+	if !ok {
+		return DirInfo{}, fmt.Errorf("no dir info for %s in snapshot %s", path, snapshot.ID)
+	}
+	if detailed && info.FileMap == nil {
+		return info, fmt.Errorf("no file map for %s in snapshot %s", path, snapshot.ID)
+	}
+	if detailed {
+		info.FileMap = copyFileMap(info.FileMap)
+	} else {
+		info.FileMap = nil
+	}
+	info.SnapshotID = snapshot.ID
+	return info, nil
+}
+
+func (s *MemoryStore) History(path string) ([]DirInfo, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	var list []DirInfo
+	for id, infos := range s.dirInfos {
+		if info, ok := infos[path]; ok {
+			info.FileMap = nil
+			info.SnapshotID = id
+			list = append(list, info)
+		}
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].SnapshotID < list[j].SnapshotID
+	})
+	return list, nil
+}
+
+func (s *MemoryStore) Size(snapshot Snapshot) (int64, error) {
+	return 0, nil
+}
+
+// ReportDir returns empty string: reports of in-memory snapshots are not saved
+func (s *MemoryStore) ReportDir(snapshot Snapshot) string {
+	return ""
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func copyFileMap(fileMap map[string]FileInfo) map[string]FileInfo {
+	if fileMap == nil {
+		return nil
+	}
+	result := make(map[string]FileInfo, len(fileMap))
+	for path, info := range fileMap {
+		result[path] = info
+	}
+	return result
+}
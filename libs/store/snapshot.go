@@ -0,0 +1,134 @@
+// Package store keeps the snapshot history: snapshot and directory info types, the SnapshotStore
+// interface and its folder, bolt and in-memory implementations
+package store
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// Snapshot is a single run of the scan over all configured directories
+type Snapshot struct {
+	FreeSpace int64        `yaml:"free-space"`
+	StartTime time.Time    `yaml:"start-time"`
+	Tags      []string     `yaml:"tags,omitempty"`   // pinned snapshots have tags (eg. "baseline")
+	Mounts    []MountUsage `yaml:"mounts,omitempty"` // usage of the mounts containing configured directories
+	InfoList  []DirInfo    `yaml:"-"`                // scanned (or loaded) directories
+	ID        string       `yaml:"-"`                // snapshot id in the store (eg. folder name)
+}
+
+// MountUsage is disk usage of the mount point
+type MountUsage struct {
+	Path        string `yaml:"path"`
+	Total       int64  `yaml:"total"`
+	Free        int64  `yaml:"free"`
+	InodesTotal uint64 `yaml:"inodes-total"`
+	InodesFree  uint64 `yaml:"inodes-free"`
+}
+
+// DirInfo contains all collected information about directory during the scan
+type DirInfo struct {
+	Path         string               `yaml:"path"`
+	Size         int64                `yaml:"size"`
+	Files        int                  `yaml:"files"`
+	Dirs         int                  `yaml:"dirs"`
+	StartTime    time.Time            `yaml:"stime"`                // the time when the scan was started
+	Hash         string               `yaml:"hash,omitempty"`       // content hash algorithm used for the file map
+	Extensions   map[string]UsageStat `yaml:"extensions,omitempty"` // breakdown by file extension
+	Categories   map[string]UsageStat `yaml:"categories,omitempty"` // breakdown by file category
+	Owners       map[string]UsageStat `yaml:"owners,omitempty"`     // breakdown by owning user
+	Groups       map[string]UsageStat `yaml:"groups,omitempty"`     // breakdown by owning group
+	MtimeAges    map[string]UsageStat `yaml:"mtime-ages,omitempty"` // histogram by file modification time
+	AtimeAges    map[string]UsageStat `yaml:"atime-ages,omitempty"` // histogram by file access time
+	Stale        []StaleSubtree       `yaml:"stale,omitempty"`      // the largest subtrees not modified since StaleSince
+	StaleSince   time.Time            `yaml:"stale-since,omitempty"`
+	WalkDuration time.Duration        `yaml:"-"`
+	FileMap      map[string]FileInfo  `yaml:"-"` // file details for detailed mode
+	SnapshotID   string               `yaml:"-"` // id of the snapshot the struct was loaded from
+}
+
+// FileInfo is a file map entry
+type FileInfo struct {
+	IsDir bool
+	Size  int64
+	Hash  []byte // content digest (hash mode only)
+}
+
+// UsageStat is the total size and number of files of an extension, a category, an owner etc.
+type UsageStat struct {
+	Size  int64 `yaml:"size"`
+	Files int   `yaml:"files"`
+}
+
+// StaleSubtree is a directory with no file modified since the stale cutoff
+type StaleSubtree struct {
+	Path   string    `yaml:"path"`
+	Size   int64     `yaml:"size"`
+	Files  int       `yaml:"files"`
+	Newest time.Time `yaml:"newest"` // modification time of the newest file
+}
+
+// NewSnapshotID returns id of the snapshot started at the time
+func NewSnapshotID(t time.Time) string {
+	switch {
+	// noinspection GoBoolExpressions
+	case runtime.GOOS == "windows":
+		return t.Format("2006-01-02 15_04_05")
+	default:
+		return t.Format("2006-01-02 15:04:05")
+	}
+}
+
+// HasTag returns true if the snapshot is tagged with the tag
+func (s Snapshot) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds the tag to the snapshot (if not added yet)
+func (s *Snapshot) AddTag(tag string) {
+	if !s.HasTag(tag) {
+		s.Tags = append(s.Tags, tag)
+	}
+}
+
+// RemoveTag removes the tag from the snapshot
+func (s *Snapshot) RemoveTag(tag string) {
+	var tags []string
+	for _, t := range s.Tags {
+		if t != tag {
+			tags = append(tags, t)
+		}
+	}
+	s.Tags = tags
+}
+
+// FindMount returns usage of the mount containing the path
+func (s Snapshot) FindMount(path string) (MountUsage, bool) {
+	var found MountUsage
+	for _, mount := range s.Mounts {
+		if IsSubPath(path, mount.Path) && len(mount.Path) > len(found.Path) {
+			found = mount
+		}
+	}
+	return found, found.Path != ""
+}
+
+// IsSubPath returns true if the path is the parent dir itself or is located inside it
+func IsSubPath(path, parent string) bool {
+	return path == parent || strings.HasPrefix(path, strings.TrimSuffix(parent, string(filepath.Separator))+string(filepath.Separator))
+}
+
+// PathHash returns the short hash of the directory path used in stored file names and keys
+func PathHash(path string) string {
+	hash := sha1.Sum([]byte(path))
+	return hex.EncodeToString(hash[0:10]) // first half of SHA1 (10 bytes)
+}
@@ -0,0 +1,70 @@
+package store
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// SnapshotStore is a storage of the snapshots history
+type SnapshotStore interface {
+	// Save creates the snapshot or updates its metadata (eg. tags)
+	Save(snapshot Snapshot) error
+	// SaveDirInfo saves directory information (and file map, if collected) of the snapshot
+	SaveDirInfo(snapshot Snapshot, info DirInfo) error
+	// Load loads snapshot metadata by snapshot id
+	Load(id string) (Snapshot, error)
+	// List returns all snapshots (older first)
+	List() ([]Snapshot, error)
+	// ListRange returns snapshots started within [from, to] time range (older first)
+	ListRange(from, to time.Time) ([]Snapshot, error)
+	// Delete deletes the snapshot with all its data
+	Delete(snapshot Snapshot) error
+	// LoadDirInfo loads information about the directory (absolute path) from the snapshot.
+	// The file map is loaded only in detailed mode
+	LoadDirInfo(snapshot Snapshot, path string, detailed bool) (DirInfo, error)
+	// History returns information about the directory (absolute path) from all snapshots (older first), without file maps
+	History(path string) ([]DirInfo, error)
+	// Size returns the storage space occupied by the snapshot
+	Size(snapshot Snapshot) (int64, error)
+	// ReportDir returns directory for report files of the snapshot
+	ReportDir(snapshot Snapshot) string
+	// Close releases the storage resources
+	Close() error
+}
+
+// SkippedError is returned along with a valid (but incomplete) result when some stored items can't be read
+type SkippedError struct {
+	Skipped []string // errors of the skipped items
+}
+
+func (e *SkippedError) Error() string {
+	return fmt.Sprintf("%d items skipped: %s", len(e.Skipped), strings.Join(e.Skipped, "; "))
+}
+
+// filterRange returns snapshots started within [from, to] time range
+func filterRange(list []Snapshot, from, to time.Time) []Snapshot {
+	var result []Snapshot
+	for _, snap := range list {
+		if !snap.StartTime.Before(from) && !snap.StartTime.After(to) {
+			result = append(result, snap)
+		}
+	}
+	return result
+}
+
+// DirSize returns total size of all files in the directory
+func DirSize(dir string) int64 {
+	var size int64
+	_ = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err == nil && !entry.IsDir() {
+			if info, err := entry.Info(); err == nil {
+				size += info.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
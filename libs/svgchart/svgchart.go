@@ -0,0 +1,88 @@
+// Package svgchart renders simple inline SVG line charts of time series
+package svgchart
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+)
+
+// Point is a single value of the series
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Chart is a line chart settings
+type Chart struct {
+	Title  string
+	Width  int
+	Height int
+	Color  string
+	Format func(value float64) string // value axis labels formatter
+}
+
+const padLeft, padRight, padTop, padBottom = 64, 12, 24, 22
+
+// Render returns SVG element of the series (points sorted by time). Empty string for less than 2 points
+func (c Chart) Render(points []Point) string {
+	if len(points) < 2 {
+		return ""
+	}
+	format := c.Format
+	if format == nil {
+		format = func(value float64) string { return fmt.Sprintf("%g", value) }
+	}
+	minT, maxT := points[0].Time, points[len(points)-1].Time
+	minV, maxV := points[0].Value, points[0].Value
+	for _, p := range points {
+		if p.Value < minV {
+			minV = p.Value
+		}
+		if p.Value > maxV {
+			maxV = p.Value
+		}
+	}
+	if maxV == minV {
+		minV, maxV = minV-1, maxV+1 // flat line in the middle
+	}
+	plotW, plotH := float64(c.Width-padLeft-padRight), float64(c.Height-padTop-padBottom)
+	spanT := maxT.Sub(minT).Seconds()
+	x := func(t time.Time) float64 {
+		if spanT == 0 {
+			return padLeft
+		}
+		return padLeft + t.Sub(minT).Seconds()/spanT*plotW
+	}
+	y := func(v float64) float64 {
+		return padTop + (maxV-v)/(maxV-minV)*plotH
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif" font-size="11">`,
+		c.Width, c.Height, c.Width, c.Height)
+	fmt.Fprintf(&sb, `<text x="%d" y="14" font-weight="bold">%s</text>`, padLeft, html.EscapeString(c.Title))
+	for _, v := range []float64{minV, (minV + maxV) / 2, maxV} { // grid lines
+		fmt.Fprintf(&sb, `<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#ddd"/>`, padLeft, y(v), c.Width-padRight, y(v))
+		fmt.Fprintf(&sb, `<text x="%d" y="%.1f" text-anchor="end" fill="#666">%s</text>`, padLeft-4, y(v)+4, html.EscapeString(format(v)))
+	}
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" fill="#666">%s</text>`, padLeft, c.Height-6, minT.Format("02 Jan 15:04"))
+	fmt.Fprintf(&sb, `<text x="%d" y="%d" text-anchor="end" fill="#666">%s</text>`, c.Width-padRight, c.Height-6, maxT.Format("02 Jan 15:04"))
+
+	var path []string
+	for _, p := range points {
+		path = append(path, fmt.Sprintf("%.1f,%.1f", x(p.Time), y(p.Value)))
+	}
+	color := c.Color
+	if color == "" {
+		color = "#2472c8"
+	}
+	fmt.Fprintf(&sb, `<polyline fill="none" stroke="%s" stroke-width="2" points="%s"/>`, color, strings.Join(path, " "))
+	for _, p := range points {
+		fmt.Fprintf(&sb, `<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"><title>%s: %s</title></circle>`,
+			x(p.Time), y(p.Value), color, p.Time.Format("02 Jan 2006 15:04"), html.EscapeString(format(p.Value)))
+	}
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
@@ -0,0 +1,80 @@
+// Package term puts the terminal into raw mode and decodes key presses for interactive screens
+package term
+
+import (
+	"bufio"
+	"errors"
+)
+
+// ErrNotSupported is returned on platforms without raw terminal mode support
+var ErrNotSupported = errors.New("raw terminal mode is not supported on this platform")
+
+// Key is a decoded key press
+type Key struct {
+	Code Code
+	Rune rune // the character for Code == Rune
+}
+
+// Code is a kind of the key
+type Code int
+
+const (
+	Rune Code = iota
+	Up
+	Down
+	Left
+	Right
+	Home
+	End
+	PageUp
+	PageDown
+	Enter
+	Backspace
+	Escape
+)
+
+// escape sequences of special keys (both CSI "ESC [" and SS3 "ESC O" forms)
+var sequences = map[string]Code{
+	"[A": Up, "[B": Down, "[C": Right, "[D": Left,
+	"OA": Up, "OB": Down, "OC": Right, "OD": Left,
+	"[H": Home, "[F": End, "OH": Home, "OF": End,
+	"[1~": Home, "[4~": End, "[7~": Home, "[8~": End,
+	"[5~": PageUp, "[6~": PageDown,
+}
+
+// ReadKey reads the next key press. Unknown escape sequences are returned as Escape
+func ReadKey(r *bufio.Reader) (Key, error) {
+	ch, _, err := r.ReadRune()
+	if err != nil {
+		return Key{}, err
+	}
+	switch ch {
+	case '\r', '\n':
+		return Key{Code: Enter}, nil
+	case 127, 8:
+		return Key{Code: Backspace}, nil
+	case 27:
+	default:
+		return Key{Code: Rune, Rune: ch}, nil
+	}
+
+	// a lone ESC press is not followed by buffered bytes
+	if r.Buffered() == 0 {
+		return Key{Code: Escape}, nil
+	}
+	seq := ""
+	for r.Buffered() > 0 && len(seq) < 8 {
+		b, err := r.ReadByte()
+		if err != nil {
+			return Key{}, err
+		}
+		seq += string(b)
+		if len(seq) > 1 && (b >= 'A' && b <= 'Z' || b == '~') {
+			break
+		}
+	}
+	if code, ok := sequences[seq]; ok {
+		return Key{Code: code}, nil
+	}
+	return Key{Code: Escape}, nil
+}
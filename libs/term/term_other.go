@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd
+
+package term
+
+// MakeRaw is not supported on this platform
+func MakeRaw(fd int) (func() error, error) {
+	return nil, ErrNotSupported
+}
+
+// Size is not supported on this platform
+func Size(fd int) (int, int, error) {
+	return 0, 0, ErrNotSupported
+}
@@ -0,0 +1,221 @@
+// Package treemap lays out hierarchical sizes as a squarified treemap and renders it to SVG
+package treemap
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"path/filepath"
+	"sort"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
+	"strings"
+)
+
+// Node is a treemap item. Size of a node with children should be the sum of the children sizes
+type Node struct {
+	Name     string
+	Title    string // tooltip text
+	Color    string // fill color of the cell
+	Size     float64
+	Children []*Node
+}
+
+// Rect is a rectangle area
+type Rect struct {
+	X, Y, W, H float64
+}
+
+// Squarify splits the rectangle into cells with areas proportional to the sizes (sorted in descending order),
+// keeping aspect ratios of the cells close to 1 (Bruls, Huizing, van Wijk algorithm)
+func Squarify(sizes []float64, rect Rect) []Rect {
+	result := make([]Rect, 0, len(sizes))
+	total := 0.0
+	for _, size := range sizes {
+		total += size
+	}
+	if total <= 0 || rect.W <= 0 || rect.H <= 0 {
+		for range sizes {
+			result = append(result, Rect{X: rect.X, Y: rect.Y})
+		}
+		return result
+	}
+	scale := rect.W * rect.H / total
+	areas := make([]float64, len(sizes))
+	for i, size := range sizes {
+		areas[i] = size * scale
+	}
+
+	free := rect
+	for start := 0; start < len(areas); {
+		side := math.Min(free.W, free.H)
+		end := start + 1
+		for end < len(areas) && worst(areas[start:end+1], side) <= worst(areas[start:end], side) {
+			end++
+		}
+		row := areas[start:end]
+		rowArea := 0.0
+		for _, area := range row {
+			rowArea += area
+		}
+		if free.W >= free.H { // vertical row at the left side
+			rowW := rowArea / free.H
+			y := free.Y
+			for _, area := range row {
+				h := area / rowW
+				result = append(result, Rect{X: free.X, Y: y, W: rowW, H: h})
+				y += h
+			}
+			free.X, free.W = free.X+rowW, free.W-rowW
+		} else { // horizontal row at the top side
+			rowH := rowArea / free.W
+			x := free.X
+			for _, area := range row {
+				w := area / rowH
+				result = append(result, Rect{X: x, Y: free.Y, W: w, H: rowH})
+				x += w
+			}
+			free.Y, free.H = free.Y+rowH, free.H-rowH
+		}
+		start = end
+	}
+	return result
+}
+
+// worst returns the highest aspect ratio of the row cells laid along the side
+func worst(row []float64, side float64) float64 {
+	sum, minArea, maxArea := 0.0, math.Inf(1), 0.0
+	for _, area := range row {
+		sum += area
+		minArea = math.Min(minArea, area)
+		maxArea = math.Max(maxArea, area)
+	}
+	if sum == 0 || minArea == 0 {
+		return math.Inf(1)
+	}
+	side2, sum2 := side*side, sum*sum
+	return math.Max(side2*maxArea/sum2, sum2/(side2*minArea))
+}
+
+// Render returns SVG of the treemap. Directory cells get a label header of headerHeight pixels when large enough
+func Render(root *Node, width, height float64, headerHeight float64) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="sans-serif" font-size="11">`,
+		width, height, width, height)
+	render(&sb, root, Rect{W: width, H: height}, headerHeight)
+	sb.WriteString(`</svg>`)
+	return sb.String()
+}
+
+func render(sb *strings.Builder, node *Node, rect Rect, headerHeight float64) {
+	if rect.W < 1 || rect.H < 1 {
+		return
+	}
+	color := node.Color
+	if color == "" {
+		color = "#cccccc"
+	}
+	fmt.Fprintf(sb, `<g><title>%s</title><rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="#fff" stroke-width="1"/>`,
+		html.EscapeString(node.Title), rect.X, rect.Y, rect.W, rect.H, color)
+	if rect.W > 30 && rect.H > 14 {
+		maxChars := int(rect.W / 6.5)
+		label := node.Name
+		if len([]rune(label)) > maxChars {
+			label = string([]rune(label)[:maxChars-1]) + "…"
+		}
+		fmt.Fprintf(sb, `<text x="%.1f" y="%.1f" fill="#000" fill-opacity="0.8">%s</text>`, rect.X+3, rect.Y+11, html.EscapeString(label))
+	}
+	sb.WriteString(`</g>`)
+
+	if len(node.Children) == 0 || rect.H <= headerHeight*2 || rect.W < 8 {
+		return
+	}
+	children := append([]*Node{}, node.Children...)
+	sort.SliceStable(children, func(i, j int) bool { return children[i].Size > children[j].Size })
+	sizes := make([]float64, len(children))
+	for i, child := range children {
+		sizes[i] = child.Size
+	}
+	inner := Rect{X: rect.X + 2, Y: rect.Y + headerHeight, W: rect.W - 4, H: rect.H - headerHeight - 2}
+	for i, cell := range Squarify(sizes, inner) {
+		render(sb, children[i], cell, headerHeight)
+	}
+}
+
+// GrowthColor returns cell color by the size change: red shades for growth, green for shrink, gray if unchanged
+func GrowthColor(size, prevSize int64, existed bool) string {
+	if !existed {
+		return "#e04f4f" // new
+	}
+	delta := size - prevSize
+	if delta == 0 {
+		return "#d9d9d9"
+	}
+	base := math.Max(float64(prevSize), 1)
+	intensity := math.Min(1, math.Abs(float64(delta))/base*4) // +25% and more is the full color
+	blend := func(from, to int) int { return from + int(float64(to-from)*intensity) }
+	if delta > 0 {
+		return fmt.Sprintf("#%02x%02x%02x", blend(0xd9, 0xe0), blend(0xd9, 0x4f), blend(0xd9, 0x4f))
+	}
+	return fmt.Sprintf("#%02x%02x%02x", blend(0xd9, 0x4f), blend(0xd9, 0xb0), blend(0xd9, 0x4f))
+}
+
+// Build builds treemap nodes of the path from the file map down to maxDepth levels, colored by growth since prevMap
+// (uncolored if nil). Entries smaller than minSize are merged into a single cell per directory. ShortPath shortens
+// the paths of the titles (eg. "~/")
+func Build(fileMap, prevMap map[string]store.FileInfo, root string, maxDepth int, minSize int64, shortPath func(string) string) *Node {
+	children := map[string][]string{}
+	for path := range fileMap {
+		if path != root {
+			parent := filepath.Dir(path)
+			children[parent] = append(children[parent], path)
+		}
+	}
+	for _, paths := range children {
+		sort.Strings(paths)
+	}
+
+	var build func(path string, depth int) *Node
+	build = func(path string, depth int) *Node {
+		info := fileMap[path]
+		prevInfo, existed := prevMap[path]
+		if prevMap == nil {
+			prevInfo, existed = info, true // nothing to compare with
+		}
+		title := fmt.Sprintf("%s\n%s", shortPath(path), human.Size(info.Size))
+		if delta := info.Size - prevInfo.Size; !existed {
+			title += " (new)"
+		} else if delta != 0 {
+			title += " (" + human.SizeSign(delta) + ")"
+		}
+		node := &Node{
+			Name:  filepath.Base(path),
+			Title: title,
+			Color: GrowthColor(info.Size, prevInfo.Size, existed),
+			Size:  float64(info.Size),
+		}
+		if !info.IsDir || depth >= maxDepth {
+			return node
+		}
+		var small int64
+		var smallCount int
+		for _, child := range children[path] {
+			if size := fileMap[child].Size; size < minSize {
+				small += size
+				smallCount++
+				continue
+			}
+			node.Children = append(node.Children, build(child, depth+1))
+		}
+		if small > 0 {
+			node.Children = append(node.Children, &Node{
+				Name:  fmt.Sprintf("(%d small)", smallCount),
+				Title: fmt.Sprintf("%d entries smaller than %s\n%s", smallCount, human.Size(minSize), human.Size(small)),
+				Color: "#eeeeee",
+				Size:  float64(small),
+			})
+		}
+		return node
+	}
+	return build(root, 0)
+}
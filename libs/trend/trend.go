@@ -0,0 +1,75 @@
+// Package trend estimates growth rates of time series
+package trend
+
+import (
+	"math"
+	"time"
+)
+
+// Point is a single measurement of the series
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// Linear returns slope (value change per second) of the least squares line fitted to the points.
+// Returns false if the slope can't be estimated (less than 2 points or all points at the same time)
+func Linear(points []Point) (float64, bool) {
+	if len(points) < 2 {
+		return 0, false
+	}
+	origin := points[0].Time
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += p.Time.Sub(origin).Seconds()
+		sumY += p.Value
+	}
+	n := float64(len(points))
+	meanX, meanY := sumX/n, sumY/n
+	var covXY, varX float64
+	for _, p := range points {
+		dx := p.Time.Sub(origin).Seconds() - meanX
+		covXY += dx * (p.Value - meanY)
+		varX += dx * dx
+	}
+	if varX == 0 {
+		return 0, false
+	}
+	return covXY / varX, true
+}
+
+// EWMA returns exponentially weighted moving average of the rates (value change per second) between
+// consecutive points. Alpha (0..1] is the weight of the latest rate. Points must be sorted by time
+func EWMA(points []Point, alpha float64) (float64, bool) {
+	var rate float64
+	ok := false
+	for i := 1; i < len(points); i++ {
+		dt := points[i].Time.Sub(points[i-1].Time).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		r := (points[i].Value - points[i-1].Value) / dt
+		if !ok {
+			rate, ok = r, true
+		} else {
+			rate = alpha*r + (1-alpha)*rate
+		}
+	}
+	return rate, ok
+}
+
+// TimeToZero returns the time until the value reaches zero with the rate (value change per second).
+// Returns false if the value doesn't decrease
+func TimeToZero(value float64, rate float64) (time.Duration, bool) {
+	if rate >= 0 {
+		return 0, false
+	}
+	if value <= 0 {
+		return 0, true
+	}
+	seconds := value / -rate
+	if seconds > float64(math.MaxInt64/int64(time.Second)) {
+		return 0, false // practically never
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
@@ -0,0 +1,160 @@
+// Package webhook posts run summaries to webhook URLs (raw JSON or Slack/Mattermost-compatible bodies)
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Payload is the run summary posted to webhooks
+type Payload struct {
+	Host      string    `json:"host"`
+	Title     string    `json:"title"`
+	Time      time.Time `json:"time"`
+	Snapshot  string    `json:"snapshot"`
+	Level     string    `json:"level"` // OK, WARNING, CRITICAL or UNKNOWN
+	FreeSpace int64     `json:"free_space"`
+	FreeText  string    `json:"-"` // formatted free space for templates
+	Dirs      []Dir     `json:"dirs"`
+	Alerts    []Alert   `json:"alerts"`
+	Changes   []Change  `json:"changes"` // top changes of detailed mode directories
+}
+
+// Dir is a scanned directory summary
+type Dir struct {
+	Path      string `json:"path"`
+	Size      int64  `json:"size"`
+	DeltaSize int64  `json:"delta_size"`
+	Files     int    `json:"files"`
+	SizeText  string `json:"-"` // formatted size for templates
+	DeltaText string `json:"-"` // formatted signed delta size for templates (empty if not changed)
+}
+
+// Alert is a triggered alert
+type Alert struct {
+	Level   string `json:"level"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// Change is a changed file or directory
+type Change struct {
+	Path      string `json:"path"`
+	Type      string `json:"type"` // added, modified or deleted
+	DeltaSize int64  `json:"delta_size"`
+	DeltaText string `json:"-"` // formatted signed delta size for templates
+}
+
+// Templates are built-in body templates. The "json" template (raw payload) is used by default
+var Templates = map[string]string{
+	"slack": `{"text": {{ json (text .) }}}`,
+	// Mattermost incoming webhooks accept Slack-compatible bodies
+	"mattermost": `{"text": {{ json (text .) }}, "username": "space-monitor"}`,
+}
+
+// text returns markdown message for chat webhooks
+func text(p Payload) string {
+	var sb strings.Builder
+	icon := map[string]string{"OK": ":white_check_mark:", "WARNING": ":warning:", "CRITICAL": ":rotating_light:", "UNKNOWN": ":grey_question:"}[p.Level]
+	fmt.Fprintf(&sb, "%s *%s* %s: %s free\n", icon, p.Title, p.Level, p.FreeText)
+	for _, alert := range p.Alerts {
+		fmt.Fprintf(&sb, "• *%s* %s: %s\n", alert.Level, alert.Rule, alert.Message)
+	}
+	for _, dir := range p.Dirs {
+		fmt.Fprintf(&sb, "`%s` %s", dir.Path, dir.SizeText)
+		if dir.DeltaText != "" {
+			fmt.Fprintf(&sb, " (%s)", dir.DeltaText)
+		}
+		sb.WriteString("\n")
+	}
+	if len(p.Changes) > 0 {
+		sb.WriteString("top changes:\n")
+		for _, change := range p.Changes {
+			fmt.Fprintf(&sb, "• %s `%s` %s\n", change.Type, change.Path, change.DeltaText)
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// Client posts payloads to the webhook URL
+type Client struct {
+	URL        string
+	Template   string        // built-in template name, Go template text or empty for raw JSON
+	Retries    int           // number of retries after a failed attempt
+	Backoff    time.Duration // delay before the first retry, doubled for each next one
+	HTTPClient *http.Client
+}
+
+// Body renders request body of the payload
+func (c Client) Body(payload Payload) ([]byte, error) {
+	if c.Template == "" || c.Template == "json" {
+		return json.Marshal(payload)
+	}
+	source, ok := Templates[c.Template]
+	if !ok {
+		source = c.Template
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"text": text,
+	}).Parse(source)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, payload); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// Send posts the payload. Network errors, 429 and 5xx responses are retried with exponential backoff
+func (c Client) Send(payload Payload) error {
+	body, err := c.Body(payload)
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", c.URL, err)
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	backoff := c.Backoff
+	for attempt := 0; ; attempt++ {
+		var retryable bool
+		retryable, err = c.post(httpClient, body)
+		if err == nil || !retryable || attempt >= c.Retries {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	if err != nil {
+		return fmt.Errorf("webhook %s: %w", c.URL, err)
+	}
+	return nil
+}
+
+// post makes a single request. Returns whether it's worth retrying on error
+func (c Client) post(httpClient *http.Client, body []byte) (bool, error) {
+	response, err := httpClient.Post(c.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	// noinspection GoUnhandledErrorResult
+	defer response.Body.Close()
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected response status %s", response.Status)
+}
@@ -1,15 +1,18 @@
 package main
 
 import (
-	"crypto/sha1"
-	"encoding/hex"
+	"crypto/sha256"
 	"fmt"
+	"github.com/cespare/xxhash/v2"
 	"github.com/fatih/color"
 	"github.com/shirou/gopsutil/disk"
 	"github.com/xeonx/timeago"
+	"hash"
+	"io"
 	"os"
 	"os/user"
 	"path/filepath"
+	"space-monitor/libs/human"
 	"strings"
 	"time"
 )
@@ -51,31 +54,11 @@ func Bold(str string, a ...interface{}) string {
 }
 
 func HumanSize(bytes int64) string {
-	var abs = func(v int64) int64 {
-		if v < 0 {
-			return -v
-		}
-		return v
-	}
-
-	const unit = 1024
-	if abs(bytes) < unit {
-		return fmt.Sprintf("%dB", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; abs(n) >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f%c", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return human.Size(bytes)
 }
 
 func HumanSizeSign(bytes int64) string {
-	str := HumanSize(bytes)
-	if !strings.HasPrefix(str, "-") {
-		return "+" + str
-	}
-	return str
+	return human.SizeSign(bytes)
 }
 
 func GetFreeSpace() (int64, error) {
@@ -98,17 +81,95 @@ func GetFreeSpace() (int64, error) {
 	//return int64(stat.Bavail) * int64(stat.Bsize), nil
 }
 
+// GetMountPoint returns mount point of the file system containing the path
+func GetMountPoint(path string) (string, error) {
+	partitions, err := disk.Partitions(true)
+	if err != nil {
+		return "", err
+	}
+	mountPoint := ""
+	for _, partition := range partitions {
+		mp := partition.Mountpoint
+		if (path == mp || strings.HasPrefix(path, strings.TrimSuffix(mp, string(filepath.Separator))+string(filepath.Separator))) &&
+			len(mp) > len(mountPoint) {
+			mountPoint = mp
+		}
+	}
+	if mountPoint == "" {
+		return "", fmt.Errorf("no mount point found for %s", path)
+	}
+	return mountPoint, nil
+}
+
+// GetMountsUsage returns usage of the mounts containing the directories (each mount once)
+func GetMountsUsage(dirs []Config_DirectorySettings) []MountUsage {
+	var result []MountUsage
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		mountPoint, err := GetMountPoint(AbsPath(dir.Path))
+		if err != nil {
+			gLogger.Println(err)
+			continue
+		}
+		if seen[mountPoint] {
+			continue
+		}
+		seen[mountPoint] = true
+		usage, err := disk.Usage(mountPoint)
+		if err != nil {
+			gLogger.Println(err)
+			continue
+		}
+		result = append(result, MountUsage{
+			Path:        mountPoint,
+			Total:       int64(usage.Total),
+			Free:        int64(usage.Free),
+			InodesTotal: usage.InodesTotal,
+			InodesFree:  usage.InodesFree,
+		})
+	}
+	return result
+}
+
+// HumanDuration formats the duration like "3 days", "2 mons"
+func HumanDuration(d time.Duration) string {
+	if d >= gTimeAgoConfig.Max {
+		return "99+ yrs"
+	}
+	if d < time.Second {
+		return gTimeAgoConfig.Zero
+	}
+	now := time.Now()
+	return strings.TrimPrefix(gTimeAgoConfig.FormatReference(now.Add(d), now), gTimeAgoConfig.FuturePrefix)
+}
+
 func GetAppDir() string {
 	path, _ := os.Executable()
 	path, _ = filepath.EvalSymlinks(path)
 	return filepath.Dir(path)
 }
 
-func GetHash(text string) string {
-	//h := xxh3.HashString128(text)
-	//return fmt.Sprintf("%x%x", h.Hi, h.Lo)
-	hash := sha1.Sum([]byte(text))
-	return hex.EncodeToString(hash[0:10]) // first half of SHA1 (10 bytes)
+// HashFile returns content digest of the file. Supported algorithms: sha256, xxhash
+func HashFile(path string, algo string) ([]byte, error) {
+	var h hash.Hash
+	switch algo {
+	case "sha256":
+		h = sha256.New()
+	case "xxhash":
+		h = xxhash.New()
+	default:
+		return nil, fmt.Errorf("unknown hash algorithm: %q", algo)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	// noinspection GoUnhandledErrorResult
+	defer file.Close()
+	if _, err := io.Copy(h, file); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
 }
 
 func AbsPath(path string) string {
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"encoding/gob"
+	"bytes"
 	"errors"
 	"flag"
 	"fmt"
@@ -10,15 +10,14 @@ import (
 	"github.com/ilyakaznacheev/cleanenv"
 	"github.com/jedib0t/go-pretty/v6/table"
 	"gopkg.in/natefinch/lumberjack.v2"
-	"gopkg.in/yaml.v2"
 	"io"
-	"io/fs"
 	"log"
 	"os"
 	"path/filepath"
-	runtime "runtime"
-	"sort"
+	"space-monitor/libs/diff"
 	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"space-monitor/libs/store"
 	"strconv"
 	"strings"
 	"time"
@@ -28,12 +27,32 @@ var (
 	gStartTime time.Time = time.Now() // application start time
 	gLogger    log.Logger
 	gCfg       Config
+	gStore     SnapshotStore
+	gReport    bytes.Buffer // colored output of the run (for notifications)
 
 	// command line arguments
 	gRepLast    = flag.Bool("replast", false, "Repeat last results")
 	gNoSave     = flag.Bool("nosave", false, "Don't save state")
 	gDaemonMode = flag.Bool("daemon", false, "Run in background")
+	gBaseline   = flag.Bool("baseline", false, "Pin current snapshot as baseline for hashed directories")
+	gTag        = flag.String("tag", "", "Tag (pin) current snapshot. Tagged snapshots are never deleted")
+	gAgainst    = flag.String("against", "", "Compare against the snapshot (tag, snapshot name, 'last' or 'prev') instead of the previous run")
 	gConfigFile = flag.String("config", "config.yaml", "Config file")
+	gDryRun     = flag.Bool("dry-run", false, "Only print what would be done (prune, migrate and import commands)")
+	gSince      = flag.String("since", "", "Time range of snapshots for list, history and find commands (eg. 7d, 12h)")
+	gRegex      = flag.Bool("regex", false, "Treat find pattern as a regular expression")
+	gMinSize    = flag.String("min-size", "", "Minimal size of found paths and treemap cells (eg. 100M)")
+	gMaxSize    = flag.String("max-size", "", "Maximal size of found paths (eg. 10G)")
+	gWarning    = flag.String("w", "", "Warning threshold of free space for check command (eg. 20% or 10G)")
+	gCritical   = flag.String("c", "", "Critical threshold of free space for check command (eg. 10% or 5G)")
+	gFresh      = flag.String("fresh", "", "Check command uses the latest snapshot instead of scanning if it is younger than this (eg. 1h)")
+	gDepth      = flag.Int("depth", 3, "Depth of the treemap")
+	gOutput     = flag.String("o", "", "Output file (treemap, export and folded commands)")
+	gInterval   = flag.String("interval", "10m", "Scan interval of the watch command (eg. 30s, 1h)")
+	gRoot       = flag.String("root", "", "Directory the imported dump describes, if it differs from the root path of the dump")
+	gTime       = flag.String("time", "", "Time of the imported snapshot (eg. 2021-03-01 12:00). Default is the ncdu export time or du output file time")
+	gPin        = flag.Bool("pin", false, "Pin imported snapshots (tag them 'imported'), so the retention policy never deletes them")
+	gMigrateTo  = flag.String("to", "", "Storage the migrate command copies all snapshots to (eg. bolt), to switch the 'storage' option")
 
 	// paths and files
 	gDataDir = GetAppDir() + "/data"
@@ -44,51 +63,47 @@ type Config struct {
 	Title        string                     `yaml:"title"`
 	Dirs         []Config_DirectorySettings `yaml:"dirs"`
 	MaxSnapshots int                        `yaml:"max-snapshots"`
+	Retention    Config_Retention           `yaml:"retention"`
 	DetailedMode bool                       `yaml:"detailed-mode"`
+	Compression  string                     `yaml:"compression"` // file map compression: gzip (default), zstd or none
+	Storage      string                     `yaml:"storage"`     // snapshot storage: folder (default), bolt or memory
+	Forecast     Config_Forecast            `yaml:"forecast"`
+	Alerts       []Config_Alert             `yaml:"alerts"`
+	Notify       Config_Notify              `yaml:"notify"`
+	Categories   map[string][]string        `yaml:"categories"` // extensions or name patterns of the file categories
+	Owners       Config_Owners              `yaml:"owners"`
+	Ages         Config_Ages                `yaml:"ages"`
 }
 
 // Config_DirectorySettings directory settings (path etc.)
 type Config_DirectorySettings struct {
-	Path string `yaml:"path"`
+	Path        string `yaml:"path"`
+	Hash        string `yaml:"hash"`          // content hash algorithm (sha256 or xxhash). Empty means no hashing
+	HashMaxSize string `yaml:"hash-max-size"` // larger files are compared by size only (eg. 1G). Empty means no limit
 }
 
-// DirInfoStruct contains all collected information about directory during the scan
-type DirInfoStruct struct {
-	Path         string    `yaml:"path"`
-	Size         int64     `yaml:"size"`
-	Files        int       `yaml:"files"`
-	Dirs         int       `yaml:"dirs"`
-	StartTime    time.Time `yaml:"stime"` // the time when the scan was started
-	walkDuration time.Duration
-	fileMap      map[string]GobFileInfo // file details for detailed mode
+// HashLimit returns the size of the largest hashed file (0 means no limit)
+func (s Config_DirectorySettings) HashLimit() int64 {
+	limit, _ := human.ParseSize(s.HashMaxSize)
+	return limit
 }
 
-// GobFileInfo - file info structure saved to the .gob file
-type GobFileInfo struct {
-	IsDir bool
-	Size  int64
+// IsDetailed returns true if the file map has to be collected for the directory
+func (s Config_DirectorySettings) IsDetailed() bool {
+	return gCfg.DetailedMode || s.Hash != ""
 }
 
-type ChangeType int
+type ChangeType = diff.Type
 
 const (
-	ADDED ChangeType = iota
-	MODIFIED
-	DELETED
+	ADDED    = diff.Added
+	MODIFIED = diff.Modified
+	DELETED  = diff.Deleted
 )
 
-type Change struct {
-	path       string
-	changeType ChangeType
-	gob        GobFileInfo
-	deltaSize  int64
-}
+type Change = diff.Change
 
-type SnapshotStruct struct {
-	FreeSpace int64     `yaml:"free-space"`
-	StartTime time.Time `yaml:"start-time"`
-	infoList  []DirInfoStruct
-}
+const BaselineTag = "baseline"
 
 func GetConfigFileAbs() string {
 	// check if config file path is absolute
@@ -105,23 +120,12 @@ func LogErr(v ...any) {
 	color.New(color.FgHiRed, color.Italic).Println(v...)
 }
 
-// GetSnapshotDirectory return current snapshot directory
-func GetSnapshotDirectory() string {
-	switch {
-	// noinspection GoBoolExpressions
-	case runtime.GOOS == "windows":
-		return gDataDir + "/" + gStartTime.Format("2006-01-02 15_04_05")
-	default:
-		return gDataDir + "/" + gStartTime.Format("2006-01-02 15:04:05")
-	}
-}
-
 func InitLogger() {
 	var logFilename = GetAppDir() + "/space-monitor.log"
 	file, err := os.OpenFile(logFilename, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 	if err != nil {
 		LogErr("error opening file:", err)
-		os.Exit(1)
+		os.Exit(ExitFatal)
 	}
 	gLogger = *log.New(file, "", log.Ldate|log.Ltime|log.Lshortfile)
 	gLogger.SetOutput(&lumberjack.Logger{
@@ -136,12 +140,67 @@ func InitConfig() {
 	// default config values
 	gCfg.MaxSnapshots = 20
 	gCfg.DetailedMode = false
+	gCfg.Forecast = Config_Forecast{Window: "30d", Method: "linear", EWMAAlpha: 0.3}
+	gCfg.Notify.TopChanges = 10
+	gCfg.Notify.Email = Config_Email{Port: 587, Security: "starttls", Mode: EmailAlert}
 
 	// load file
 	err := cleanenv.ReadConfig(GetConfigFileAbs(), &gCfg)
 	if err != nil {
 		LogErr(err)
 	}
+
+	if _, err := store.FileMapExt(gCfg.Compression); err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
+	}
+	for _, dir := range gCfg.Dirs {
+		if dir.Hash != "" && dir.Hash != "sha256" && dir.Hash != "xxhash" {
+			LogErr("unknown hash algorithm", dir.Hash, "for", dir.Path, "(sha256 or xxhash expected)")
+			os.Exit(ExitFatal)
+		}
+		if dir.HashMaxSize != "" {
+			if limit, err := human.ParseSize(dir.HashMaxSize); err != nil || limit <= 0 {
+				LogErr("invalid hash-max-size", dir.HashMaxSize, "for", dir.Path)
+				os.Exit(ExitFatal)
+			}
+		}
+	}
+	if err := gCfg.Forecast.Validate(); err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
+	}
+	for _, alert := range gCfg.Alerts {
+		if err := alert.Validate(); err != nil {
+			LogErr(err)
+			os.Exit(ExitFatal)
+		}
+	}
+	for i := range gCfg.Notify.Webhooks {
+		hook := &gCfg.Notify.Webhooks[i]
+		if hook.Retries == 0 {
+			hook.Retries = 3
+		}
+		if hook.Backoff == "" {
+			hook.Backoff = "2s"
+		}
+	}
+	if err := gCfg.Notify.Validate(); err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
+	}
+	if err := gCfg.Owners.Validate(); err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
+	}
+	if err := gCfg.Ages.Validate(); err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
+	}
+	if err := InitCategories(); err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
+	}
 }
 
 func InitDataDirs() {
@@ -149,126 +208,128 @@ func InitDataDirs() {
 	if err != nil && !errors.Is(err, os.ErrExist) {
 		LogErr(err)
 	}
-	if !*gNoSave && !*gRepLast {
-		err = os.Mkdir(GetSnapshotDirectory(), 0777)
-		if err != nil && !errors.Is(err, os.ErrExist) {
-			LogErr(err)
-		}
+}
+
+func InitStore() {
+	var err error
+	gStore, err = OpenStore(gCfg)
+	if err != nil {
+		LogErr(err)
+		os.Exit(ExitFatal)
 	}
 }
 
 func InitStdoutSaver() {
+	var stdout io.Writer = os.Stdout
+	if gCfg.Notify.Email.Enabled() && color.NoColor {
+		// keep colors for the email report, but not for the non-terminal output (eg. cron)
+		color.NoColor = false
+		stdout = FilterFunc(os.Stdout, func(bytes []byte) []byte {
+			return []byte(stripansi.Strip(string(bytes)))
+		})
+	}
+	color.Output = io.MultiWriter(stdout, &gReport)
+	fmt2.OutWriter = color.Output
+
 	if *gNoSave || *gRepLast { // don't save report.txt when nosave mode or replast option
 		return
 	}
-	reportFile, _ := os.OpenFile(GetSnapshotDirectory()+"/report.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
-	reportFlBW, _ := os.OpenFile(GetSnapshotDirectory()+"/report-bw.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	reportDir := gStore.ReportDir(SnapshotStruct{ID: store.NewSnapshotID(gStartTime)})
+	if reportDir == "" {
+		return // the store doesn't keep reports
+	}
+	if err := os.MkdirAll(reportDir, 0777); err != nil {
+		LogErr(err)
+	}
+	reportFile, _ := os.OpenFile(reportDir+"/report.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
+	reportFlBW, _ := os.OpenFile(reportDir+"/report-bw.txt", os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0666)
 	bwWriter := FilterFunc(reportFlBW, func(bytes []byte) []byte {
 		str := stripansi.Strip(string(bytes))
 		return []byte(str)
 	})
-	multiWriter := io.MultiWriter(os.Stdout, bwWriter, reportFile)
+	multiWriter := io.MultiWriter(stdout, &gReport, bwWriter, reportFile)
 	color.Output = multiWriter
 	fmt2.OutWriter = multiWriter
 }
 
-// SaveDirInfo saves struct to .dat (yaml) and .gob (filemap) files
-func SaveDirInfo(dirInfo DirInfoStruct) {
-	pathHash := GetHash(dirInfo.Path)
-	dirInfoFilePath := fmt.Sprintf(GetSnapshotDirectory()+"/dirinfo-%s.dat", pathHash)
-	dirInfoFile, err := os.OpenFile(dirInfoFilePath, os.O_WRONLY|os.O_CREATE, 0666)
-	// noinspection GoUnhandledErrorResult
-	defer dirInfoFile.Close()
-	if err != nil {
-		LogErr(err)
-		os.Exit(1)
-	}
-
-	if gCfg.DetailedMode {
-		encodeFile, err := os.Create(strings.Replace(dirInfoFilePath, ".dat", ".gob", 1))
-		// noinspection GoUnhandledErrorResult
-		defer encodeFile.Close()
-		if err != nil {
-			LogErr(err)
-			return
-		}
-		encoder := gob.NewEncoder(encodeFile)
-		if err := encoder.Encode(dirInfo.fileMap); err != nil {
-			LogErr(err)
-		}
-	}
-
-	bytes, _ := yaml.Marshal(dirInfo)
-	_, err = dirInfoFile.WriteString(string(bytes))
-	if err != nil {
-		LogErr(err)
-	}
-}
-
-// LoadPrevDirInfo loads previous dir info struct from previous snapshot directory
-func LoadPrevDirInfo(dir string, stepsBack int) (DirInfoStruct, error) {
-	files, _ := filepath.Glob(gDataDir + fmt.Sprintf("/*/dirinfo-%s.dat", GetHash(AbsPath(dir))))
-	if files == nil {
-		fmt2.Println("no dirinfo files for", color.BlueString(dir), "is it first run?")
+// LoadPrevDirInfo loads previous dir info struct from previous snapshot
+func LoadPrevDirInfo(dir Config_DirectorySettings, stepsBack int) (DirInfoStruct, error) {
+	history, err := gStore.History(AbsPath(dir.Path))
+	var skipped *store.SkippedError
+	if errors.As(err, &skipped) {
+		gLogger.Println(err) // use what is readable
+	} else if err != nil {
+		return DirInfoStruct{}, err
+	}
+	if history == nil {
+		fmt2.Println("no dirinfo files for", color.BlueString(dir.Path), "is it first run?")
 		return DirInfoStruct{}, errors.New("no prev dirinfo files")
 	}
-	sort.Strings(files)
-	index := len(files) - 1 - stepsBack
-	if index < 0 || index >= len(files) {
+	index := len(history) - 1 - stepsBack
+	if index < 0 || index >= len(history) {
 		return DirInfoStruct{}, errors.New("out of bounds dirinfo array. index=" + strconv.Itoa(index))
 	}
-	last := files[index]
-	bytes, _ := os.ReadFile(last)
-	info := DirInfoStruct{}
-	err := yaml.Unmarshal(bytes, &info)
-	if err != nil {
-		LogErr(err)
-	}
+	return gStore.LoadDirInfo(SnapshotStruct{ID: history[index].SnapshotID}, AbsPath(dir.Path), dir.IsDetailed())
+}
 
-	if gCfg.DetailedMode {
-		decodeFile, err := os.Open(strings.Replace(last, ".dat", ".gob", 1))
-		if err != nil {
-			return info, err
-		}
-		// noinspection GoUnhandledErrorResult
-		defer decodeFile.Close()
-		decoder := gob.NewDecoder(decodeFile)
-		err = decoder.Decode(&info.fileMap)
-		if err != nil {
-			return info, err
-		}
-	}
+// LoadDirInfo loads dir info struct of the directory from the given snapshot
+func LoadDirInfo(snapshot SnapshotStruct, dir Config_DirectorySettings) (DirInfoStruct, error) {
+	return gStore.LoadDirInfo(snapshot, AbsPath(dir.Path), dir.IsDetailed())
+}
 
-	return info, nil
+// ScanOptions select what ProcessDirectory collects
+type ScanOptions struct {
+	StartTime time.Time // start of the scan (file ages are relative to it)
+	Expected  int       // number of the directory entries known from the previous scan (0 if unknown)
+	FileMap   bool      // collect the file map (detailed mode)
+	Stats     bool      // collect the breakdowns, owners and file ages
 }
 
-// ProcessDirectory collects full directory information
-func ProcessDirectory(dir string) (DirInfoStruct, error) {
-	dir = AbsPath(dir)
+// ProcessDirectory collects directory information selected by the options
+func ProcessDirectory(dirSettings Config_DirectorySettings, options ScanOptions) (DirInfoStruct, error) {
+	dir := AbsPath(dirSettings.Path)
 	var info = DirInfoStruct{
 		Path:      dir,
-		StartTime: gStartTime,
-		fileMap:   map[string]GobFileInfo{},
+		StartTime: options.StartTime,
+		Hash:      dirSettings.Hash,
+	}
+	if options.FileMap {
+		info.FileMap = map[string]GobFileInfo{}
+	}
+	hashLimit := dirSettings.HashLimit()
+	var ages *ageCollector
+	if options.Stats {
+		ages = newAgeCollector(dir, info.StartTime)
 	}
+	gProgress.StartDir(dir, options.Expected)
+	defer gProgress.EndDir()
 	err := filepath.Walk(dir, func(path string, fileInfo os.FileInfo, err error) error {
 		if err != nil {
 			gLogger.Println(err)
 			//return err // return error if you want to break walking
 		} else {
-			if gCfg.DetailedMode {
+			gProgress.Add(path, fileInfo.IsDir(), fileInfo.Size())
+			if info.FileMap != nil {
 				var size int64 = 0
 				if !fileInfo.IsDir() {
 					size = fileInfo.Size()
 				}
-				info.fileMap[path] = GobFileInfo{IsDir: fileInfo.IsDir(), Size: size}
+				gobInfo := GobFileInfo{IsDir: fileInfo.IsDir(), Size: size}
+				if dirSettings.Hash != "" && fileInfo.Mode().IsRegular() && (hashLimit == 0 || size <= hashLimit) {
+					gobInfo.Hash, err = HashFile(path, dirSettings.Hash)
+					if err != nil {
+						gLogger.Println(err)
+					}
+				}
+				info.FileMap[path] = gobInfo
 
 				// increment size of all parent dirs
 				current := filepath.Dir(path)
 				for strings.HasPrefix(current, dir) {
 					//fmt2.Println("current:", current)
-					if gobInfo, ok := info.fileMap[current]; ok {
+					if gobInfo, ok := info.FileMap[current]; ok {
 						gobInfo.Size += size
-						info.fileMap[current] = gobInfo
+						info.FileMap[current] = gobInfo
 					} else {
 						LogErr("Unknown parent:", current, "for file:", path)
 					}
@@ -281,110 +342,154 @@ func ProcessDirectory(dir string) (DirInfoStruct, error) {
 			} else {
 				info.Files++
 				info.Size += fileInfo.Size()
+				if options.Stats {
+					addBreakdown(&info, path, fileInfo.Size())
+					addOwner(&info, fileInfo)
+					ages.add(&info, path, fileInfo)
+				}
 			}
 		}
 		return nil
 	})
+	if options.Stats {
+		ages.finish(&info)
+		finishBreakdown(&info)
+	}
 
 	return info, err
 }
 
-func SaveSnapshot(snapshot SnapshotStruct) {
-	snapshotFile, err := os.OpenFile(GetSnapshotDirectory()+"/snapshot.dat", os.O_WRONLY|os.O_CREATE, 0666)
-	// noinspection GoUnhandledErrorResult
-	defer snapshotFile.Close()
-	if err != nil {
-		LogErr("error opening snapshot file: ", err)
-		os.Exit(1)
+// ScanSnapshot scans all configured directories and saves the snapshot started at the start time (its id) with the tags
+// (unless -nosave). The scan progress is estimated by the previous snapshot (with loaded directories). Scanned (if set)
+// is called after each directory, eg. to print its changes
+func ScanSnapshot(prev SnapshotStruct, startTime time.Time, tags []string, scanned func(info DirInfoStruct)) (SnapshotStruct, error) {
+	freeSpace, _ := GetFreeSpace()
+	snapshot := SnapshotStruct{
+		FreeSpace: freeSpace,
+		StartTime: startTime,
+		Mounts:    GetMountsUsage(gCfg.Dirs),
+		ID:        store.NewSnapshotID(startTime),
+	}
+	for _, tag := range tags {
+		snapshot.AddTag(tag)
+	}
+	save := !*gNoSave
+	if save {
+		if err := gStore.Save(snapshot); err != nil {
+			return snapshot, fmt.Errorf("error saving snapshot: %w", err)
+		}
 	}
-	bytes, _ := yaml.Marshal(snapshot)
-	_, err = snapshotFile.WriteString(string(bytes))
-	if err != nil {
-		LogErr(err)
+	expected := ExpectedEntries(prev)
+	gProgress.Begin(len(gCfg.Dirs))
+	defer gProgress.Finish()
+	for _, dir := range gCfg.Dirs {
+		start := time.Now()
+		options := ScanOptions{StartTime: startTime, Expected: expected[AbsPath(dir.Path)], FileMap: dir.IsDetailed(), Stats: true}
+		info, err := ProcessDirectory(dir, options)
+		if err != nil {
+			return snapshot, err
+		}
+		info.WalkDuration = time.Since(start).Round(time.Millisecond)
+		snapshot.InfoList = append(snapshot.InfoList, info)
+		if save {
+			if err := gStore.SaveDirInfo(snapshot, info); err != nil {
+				return snapshot, fmt.Errorf("error saving dir info: %w", err)
+			}
+		}
+		if scanned != nil {
+			scanned(info)
+		}
 	}
+	return snapshot, nil
 }
 
-func LoadPrevSnapshot(stepsBack int) SnapshotStruct {
-	files, err := filepath.Glob(gDataDir + fmt.Sprintf("/*/snapshot.dat"))
+// ErrNoSnapshots is returned when the store has no snapshots yet (first run)
+var ErrNoSnapshots = errors.New("no snapshots found")
+
+// LoadPrevSnapshot loads the snapshot stepsBack steps before the last one
+func LoadPrevSnapshot(stepsBack int) (SnapshotStruct, error) {
+	list, err := ListSnapshots()
 	if err != nil {
-		LogErr(err)
-		os.Exit(1)
+		return SnapshotStruct{}, err
 	}
-	if files == nil {
-		fmt2.Println("no snapshot files; is it first run?")
-		return SnapshotStruct{}
-	}
-	sort.Strings(files)
-	index := len(files) - 1 - stepsBack
-	if index < 0 || index >= len(files) {
-		LogErr("out of bounds snapshot array. len(files):", len(files), "stepsBack:", stepsBack)
-		return SnapshotStruct{}
-	}
-	last := files[index]
-	bytes, _ := os.ReadFile(last)
-	snap := SnapshotStruct{}
-	err = yaml.Unmarshal(bytes, &snap)
-	if err != nil {
-		LogErr(err)
+	if list == nil {
+		return SnapshotStruct{}, ErrNoSnapshots
 	}
-	return snap
+	index := len(list) - 1 - stepsBack
+	if index < 0 || index >= len(list) {
+		return SnapshotStruct{}, fmt.Errorf("out of bounds snapshot array. len(list): %d stepsBack: %d", len(list), stepsBack)
+	}
+	return list[index], nil
 }
 
-func DeleteOldSnapshots() {
-	files, _ := os.ReadDir(gDataDir)
-	var dirs []fs.DirEntry
-	for _, file := range files {
-		if file.IsDir() {
-			dirs = append(dirs, file)
-		}
+// ListSnapshots loads all snapshots of the store (older first). Unreadable snapshots are logged and skipped
+func ListSnapshots() ([]SnapshotStruct, error) {
+	list, err := gStore.List()
+	var skipped *store.SkippedError
+	if errors.As(err, &skipped) {
+		gLogger.Println(err)
+		return list, nil
 	}
-	if len(dirs) <= gCfg.MaxSnapshots {
-		return // MaxSnapshots not exceeded. No need to delete
+	return list, err
+}
+
+// LoadTaggedSnapshot loads the latest snapshot tagged with the tag. Returns false if there is no such snapshot
+func LoadTaggedSnapshot(tag string) (SnapshotStruct, bool, error) {
+	list, err := ListSnapshots()
+	for i := len(list) - 1; i >= 0; i-- {
+		if list[i].HasTag(tag) {
+			return list[i], true, err
+		}
 	}
-	sort.Slice(dirs, func(i, j int) bool { // sort dirs (older first)
-		return strings.Compare(dirs[i].Name(), dirs[j].Name()) < 0
-	})
+	return SnapshotStruct{}, false, err
+}
 
-	// delete first (len(dirs) - MaxSnapshots) folders
-	for _, dir := range dirs[0 : len(dirs)-gCfg.MaxSnapshots] {
-		err := os.RemoveAll(gDataDir + "/" + dir.Name())
-		if err != nil {
+// UntagSnapshots removes the tag from all snapshots except the given one
+func UntagSnapshots(tag string, keepID string) {
+	list, err := ListSnapshots()
+	if err != nil {
+		LogErr(err)
+	}
+	for _, snapshot := range list {
+		if snapshot.ID == keepID || !snapshot.HasTag(tag) {
+			continue
+		}
+		snapshot.RemoveTag(tag)
+		if err := gStore.Save(snapshot); err != nil {
 			LogErr(err)
-			return
 		}
 	}
 }
 
-// Diff collects and returns Change list
-func Diff(prevDirInfo, currDirInfo DirInfoStruct) []Change {
-	var prevMap = prevDirInfo.fileMap
-	var currMap = currDirInfo.fileMap
-
-	if len(prevMap) == 0 {
-		return []Change{} // skip empty map (eg. when first run)
+// FindSnapshot finds snapshot by reference: 'last', 'prev', snapshot id (or its prefix) or tag
+func FindSnapshot(ref string) (SnapshotStruct, error) {
+	list, err := ListSnapshots()
+	if err != nil {
+		return SnapshotStruct{}, err
 	}
-
-	var changes []Change // all changes will be collected here
-
-	for key := range currMap {
-		if _, ok := prevMap[key]; !ok { // exists in current and doesn't exist in prev - means new file ADDED
-			changes = append(changes, Change{key, ADDED, currMap[key], currMap[key].Size})
-		} else {
-			if currMap[key].Size != prevMap[key].Size { // exists in both but size is changed - means file MODIFIED
-				changes = append(changes, Change{key, MODIFIED, currMap[key], currMap[key].Size - prevMap[key].Size})
-			}
+	if len(list) == 0 {
+		return SnapshotStruct{}, ErrNoSnapshots
+	}
+	switch ref {
+	case "last", "latest":
+		return list[len(list)-1], nil
+	case "prev":
+		if len(list) < 2 {
+			return SnapshotStruct{}, errors.New("no previous snapshot")
 		}
+		return list[len(list)-2], nil
 	}
-	for key := range prevMap {
-		if _, ok := currMap[key]; !ok { // exists in previous and doesn't exist in current - means file DELETED
-			changes = append(changes, Change{key, DELETED, prevMap[key], 0 - prevMap[key].Size})
+	for i := len(list) - 1; i >= 0; i-- { // newer first
+		if list[i].HasTag(ref) || strings.HasPrefix(list[i].ID, ref) {
+			return list[i], nil
 		}
 	}
+	return SnapshotStruct{}, fmt.Errorf("snapshot %q not found", ref)
+}
 
-	sort.Slice(changes, func(i, j int) bool { // sort changes by path
-		return strings.Compare(changes[i].path, changes[j].path) < 0
-	})
-	return changes
+// Diff collects and returns Change list
+func Diff(prevDirInfo, currDirInfo DirInfoStruct) []Change {
+	return diff.Files(prevDirInfo.FileMap, currDirInfo.FileMap)
 }
 
 // PrintDiff calculates and prints directory structure changes
@@ -406,18 +511,20 @@ func PrintDiff(prevDirInfo, currDirInfo DirInfoStruct) {
 	color.New(color.Bold, color.FgWhite).Printf("\n\nDiff of %s:\n\n", currDirInfo.Path)
 
 	for _, change := range changes {
-		relPath := strings.Replace(change.path, AbsPath(currDirInfo.Path), "", 1)
+		relPath := strings.Replace(change.Path, AbsPath(currDirInfo.Path), "", 1)
 		var colMain, colInvr *color.Color
 		var symbol, icon string
-		switch change.changeType {
+		switch change.Type {
 		case ADDED:
 			symbol = "+"
 			colMain = colorAddMain
 			colInvr = colorAddInvr
 		case MODIFIED:
 			symbol = "↗"
-			if change.deltaSize < 0 {
+			if change.Delta < 0 {
 				symbol = "↘"
+			} else if change.Delta == 0 {
+				symbol = "≠" // content changed
 			}
 			colMain = colorModMain
 			colInvr = colorModInvr
@@ -427,7 +534,7 @@ func PrintDiff(prevDirInfo, currDirInfo DirInfoStruct) {
 			colInvr = colorDelInvr
 		}
 
-		switch change.gob.IsDir {
+		switch change.File.IsDir {
 		case true:
 			icon = "🗀"
 		case false:
@@ -436,9 +543,9 @@ func PrintDiff(prevDirInfo, currDirInfo DirInfoStruct) {
 
 		colMain.Printf(" %-2s ", icon)
 		colMain.Printf("%-2s", symbol)
-		colMain.Printf("%-10s", HumanSize(change.gob.Size))
-		if change.changeType == MODIFIED {
-			colorDeltaSz.Printf("%-11s", HumanSizeSign(change.deltaSize))
+		colMain.Printf("%-10s", HumanSize(change.File.Size))
+		if change.Type == MODIFIED && change.Delta != 0 {
+			colorDeltaSz.Printf("%-11s", HumanSizeSign(change.Delta))
 		} else {
 			colorDeltaSz.Printf("%-11s", "")
 		}
@@ -448,21 +555,32 @@ func PrintDiff(prevDirInfo, currDirInfo DirInfoStruct) {
 	}
 }
 
-// PrintTable prints summarized table
-func PrintTable(prevSnapshot, currSnapshot SnapshotStruct) {
-	title := gCfg.Title
-	if title == "" {
-		title, _ = os.Hostname()
-		title = strings.ToUpper(title)
+// ReportTitle returns the configured title or the host name
+func ReportTitle() string {
+	if gCfg.Title != "" {
+		return gCfg.Title
 	}
+	title, _ := os.Hostname()
+	return strings.ToUpper(title)
+}
+
+// PrintTable prints summarized table
+func PrintTable(prevSnapshot, currSnapshot SnapshotStruct, trends Trends) {
+	tableWriter := SummaryTable(prevSnapshot, currSnapshot, nil, time.Since(gStartTime), trends)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.Render()
+}
+
+// SummaryTable returns summarized table of the snapshots. Values changed since the lastCycle snapshot (if any) are highlighted
+func SummaryTable(prevSnapshot, currSnapshot SnapshotStruct, lastCycle *SnapshotStruct, elapsed time.Duration, trends Trends) table.Writer {
+	title := ReportTitle()
 	tableWriter := table.NewWriter()
-	tableWriter.SetTitle("%s - %d directories", color.New(color.Bold, color.FgHiYellow).Sprintf(title), len(currSnapshot.infoList))
+	tableWriter.SetTitle("%s - %d directories", color.New(color.Bold, color.FgHiYellow).Sprintf(title), len(currSnapshot.InfoList))
 	tableWriter.SetStyle(table.StyleRounded)
-	tableWriter.SetOutputMirror(fmt2.OutWriter)
-	tableWriter.AppendHeader(table.Row{"path", "size", "dirs", "files", "walk time"})
+	tableWriter.AppendHeader(table.Row{"path", "size", "dirs", "files", "walk time", "growth/day", "est. full in"})
 
-	for i, currDirInfo := range currSnapshot.infoList {
-		prevDirInfo := prevSnapshot.infoList[i]
+	for i, currDirInfo := range currSnapshot.InfoList {
+		prevDirInfo := prevSnapshot.InfoList[i]
 
 		var deltaSize, deltaDirs, deltaFiles string
 
@@ -478,12 +596,21 @@ func PrintTable(prevSnapshot, currSnapshot SnapshotStruct) {
 			}
 		}
 
+		var lastDirInfo DirInfoStruct
+		if lastCycle != nil && i < len(lastCycle.InfoList) {
+			lastDirInfo = lastCycle.InfoList[i]
+		}
+		changed := lastDirInfo.Path != ""
+
+		growth := trends.Dirs[currDirInfo.Path]
 		tableWriter.AppendRow([]interface{}{
 			color.HiBlueString(shorifyPath(currDirInfo.Path)),
-			HumanSize(currDirInfo.Size) + color.HiMagentaString(deltaSize),
-			strconv.Itoa(currDirInfo.Dirs) + deltaDirs,
-			strconv.Itoa(currDirInfo.Files) + deltaFiles,
-			currDirInfo.walkDuration,
+			highlightIf(changed && lastDirInfo.Size != currDirInfo.Size, HumanSize(currDirInfo.Size)) + color.HiMagentaString(deltaSize),
+			highlightIf(changed && lastDirInfo.Dirs != currDirInfo.Dirs, strconv.Itoa(currDirInfo.Dirs)) + deltaDirs,
+			highlightIf(changed && lastDirInfo.Files != currDirInfo.Files, strconv.Itoa(currDirInfo.Files)) + deltaFiles,
+			currDirInfo.WalkDuration,
+			growth.PerDay(growth.Rate),
+			growth.FullInString(),
 		})
 	}
 
@@ -493,6 +620,7 @@ func PrintTable(prevSnapshot, currSnapshot SnapshotStruct) {
 			ColorHeader("prev stime (t₀)"),
 			ColorPale(prevSnapshot.StartTime.Format("02 Jan 15:04")),
 			ColorPale(TimeAgo(prevSnapshot.StartTime)),
+			ColorPale(strings.Join(prevSnapshot.Tags, ",")),
 		})
 	}
 	tableWriter.AppendRow(table.Row{
@@ -507,21 +635,45 @@ func PrintTable(prevSnapshot, currSnapshot SnapshotStruct) {
 		deltaFreeSpace = " " + HumanSizeSign(currSnapshot.FreeSpace-prevSnapshot.FreeSpace)
 	}
 
+	freeSpace := color.HiGreenString(HumanSize(currSnapshot.FreeSpace))
+	if lastCycle != nil && lastCycle.FreeSpace != currSnapshot.FreeSpace {
+		freeSpace = highlightIf(true, HumanSize(currSnapshot.FreeSpace))
+	}
+	growth := trends.FreeSpace
 	tableWriter.AppendRow(table.Row{
 		"FREE SPACE",
-		color.HiGreenString(HumanSize(currSnapshot.FreeSpace)) + color.HiMagentaString(deltaFreeSpace),
+		freeSpace + color.HiMagentaString(deltaFreeSpace),
 		"", "",
-		time.Since(gStartTime).Round(time.Millisecond),
+		elapsed.Round(time.Millisecond),
+		growth.PerDay(-growth.Rate),
+		growth.FullInString(),
 	})
-	tableWriter.Render()
+	return tableWriter
+}
+
+// highlightIf marks the value changed since the last watch cycle
+func highlightIf(changed bool, value string) string {
+	if !changed {
+		return value
+	}
+	return color.New(color.BgYellow, color.FgBlack).Sprint(value)
 }
 
 func main() {
 	flag.Parse()
+	command, args := ParseCommand()
 
 	InitLogger()
 	InitConfig()
 	InitDataDirs()
+	InitStore()
+
+	if command != "" {
+		code := RunCommand(command, args)
+		_ = gStore.Close()
+		os.Exit(code)
+	}
+
 	InitStdoutSaver()
 
 	fmt2.Println()
@@ -534,64 +686,109 @@ func main() {
 		stepsBack = 1 // pre-previous
 	}
 
-	prevSnapshot := LoadPrevSnapshot(stepsBack)
-
-	// calculate free space
-	_freeSpace, _ := GetFreeSpace()
-
-	var currSnapshot = SnapshotStruct{
-		FreeSpace: _freeSpace,
-		StartTime: gStartTime,
+	prevSnapshot, err := LoadPrevSnapshot(stepsBack)
+	if errors.Is(err, ErrNoSnapshots) {
+		fmt2.Println("no snapshot files; is it first run?")
+	} else if err != nil {
+		LogErr(err)
+	}
+	if *gAgainst != "" {
+		prevSnapshot, err = FindSnapshot(*gAgainst)
+		if err != nil {
+			LogErr(err)
+			os.Exit(ExitFatal)
+		}
 	}
 
+	var currSnapshot SnapshotStruct
 	if *gRepLast {
-		currSnapshot = LoadPrevSnapshot(0)
+		if currSnapshot, err = LoadPrevSnapshot(0); err != nil {
+			LogErr(err)
+		}
 	}
 
-	if !*gNoSave && !*gRepLast {
-		SaveSnapshot(currSnapshot)
+	// hashed directories are compared against the pinned baseline snapshot (if any)
+	baselineSnapshot, hasBaseline, err := LoadTaggedSnapshot(BaselineTag)
+	if err != nil {
+		LogErr(err)
+	}
+	if hasBaseline && (*gBaseline || baselineSnapshot.ID == currSnapshot.ID) {
+		hasBaseline = false // current snapshot is the baseline itself
 	}
 
-	// for each directory
+	// load previous state of the directories
 	for _, dir := range gCfg.Dirs {
-		// load previous state of the directory
-		prevDirInfo, _ := LoadPrevDirInfo(dir.Path, stepsBack)
+		var prevDirInfo DirInfoStruct
+		switch {
+		case *gAgainst != "":
+			prevDirInfo, _ = LoadDirInfo(prevSnapshot, dir)
+		case dir.Hash != "" && hasBaseline:
+			prevDirInfo, _ = LoadDirInfo(baselineSnapshot, dir)
+		default:
+			prevDirInfo, _ = LoadPrevDirInfo(dir, stepsBack)
+		}
+		prevSnapshot.InfoList = append(prevSnapshot.InfoList, prevDirInfo)
+	}
 
-		// calculate current state
-		var currDirInfo DirInfoStruct
-		start := time.Now()
-		if *gRepLast {
-			currDirInfo, _ = LoadPrevDirInfo(dir.Path, 0)
-		} else {
-			var err error
-			currDirInfo, err = ProcessDirectory(dir.Path)
-			if err != nil {
-				LogErr(err)
-				//continue
+	// print diff of each directory as soon as it is scanned
+	printDiff := func(currDirInfo DirInfoStruct) {
+		for i, dir := range gCfg.Dirs {
+			if dir.IsDetailed() && AbsPath(dir.Path) == currDirInfo.Path {
+				PrintDiff(prevSnapshot.InfoList[i], currDirInfo)
+				return
 			}
 		}
-		currDirInfo.walkDuration = time.Since(start).Round(time.Millisecond)
-
-		prevSnapshot.infoList = append(prevSnapshot.infoList, prevDirInfo)
-		currSnapshot.infoList = append(currSnapshot.infoList, currDirInfo)
+	}
 
-		if !*gNoSave && !*gRepLast {
-			SaveDirInfo(currDirInfo)
+	if *gRepLast {
+		for _, dir := range gCfg.Dirs {
+			currDirInfo, _ := LoadPrevDirInfo(dir, 0)
+			currSnapshot.InfoList = append(currSnapshot.InfoList, currDirInfo)
+			printDiff(currDirInfo)
 		}
-
-		// print diff
-		if gCfg.DetailedMode {
-			PrintDiff(prevDirInfo, currDirInfo)
+	} else {
+		var tags []string
+		if *gBaseline {
+			tags = append(tags, BaselineTag)
+		}
+		if *gTag != "" {
+			tags = append(tags, *gTag)
 		}
-	} // dir loop
+		stopProgress := gProgress.Report()
+		currSnapshot, err = ScanSnapshot(prevSnapshot, gStartTime, tags, printDiff) // report.txt of the run is written to its folder (InitStdoutSaver)
+		stopProgress()
+		if err != nil {
+			LogErr(err)
+			os.Exit(ExitFatal)
+		}
+		if *gBaseline && !*gNoSave {
+			UntagSnapshots(BaselineTag, currSnapshot.ID) // the baseline moves to the new snapshot
+		}
+	}
 
 	// print result table
 	fmt2.Println()
-	PrintTable(prevSnapshot, currSnapshot)
+	trends := NewTrends(currSnapshot)
+	PrintTable(prevSnapshot, currSnapshot, trends)
+	PrintBreakdown(prevSnapshot, currSnapshot)
+	PrintOwners(prevSnapshot, currSnapshot)
+
+	alerts := EvaluateAlerts(gCfg.Alerts, prevSnapshot, currSnapshot, trends)
+	PrintAlerts(alerts)
+	if !*gRepLast {
+		Notify(prevSnapshot, currSnapshot, alerts)
+	}
+	if !*gNoSave && !*gRepLast {
+		SaveHTMLReport(prevSnapshot, currSnapshot, alerts, trends)
+	}
 
 	fmt.Println()
 
 	DeleteOldSnapshots()
+	if !*gNoSave && !*gRepLast {
+		SaveHTMLIndex()
+	}
+	_ = gStore.Close()
 
 	//width, height, _ := terminal.GetSize(0)
 	//fmt2.Println("size", width, height)
@@ -602,4 +799,6 @@ func main() {
 			time.Sleep(time.Millisecond * 100)
 		}
 	}
+
+	os.Exit(int(MaxAlertLevel(alerts))) // 0 ok, 1 warning, 2 critical, 3 unknown
 }
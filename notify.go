@@ -0,0 +1,159 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"space-monitor/libs/human"
+	"space-monitor/libs/webhook"
+	"strings"
+	"time"
+)
+
+// Config_Notify notification settings
+type Config_Notify struct {
+	OnStateChange bool             `yaml:"on-state-change"` // notify only when the alert level changes
+	TopChanges    int              `yaml:"top-changes"`     // number of the largest changes in notifications
+	Webhooks      []Config_Webhook `yaml:"webhooks"`
+	Email         Config_Email     `yaml:"email"`
+}
+
+// Config_Webhook webhook notifier settings
+type Config_Webhook struct {
+	URL      string `yaml:"url"`
+	Template string `yaml:"template"` // json (default), slack, mattermost or a Go template file
+	Retries  int    `yaml:"retries"`  // 3 by default, -1 disables retries
+	Backoff  string `yaml:"backoff"`  // delay before the first retry (doubled for each next one)
+}
+
+// Validate checks the notification settings
+func (n Config_Notify) Validate() error {
+	for _, hook := range n.Webhooks {
+		if hook.URL == "" {
+			return errors.New("webhook url is not set")
+		}
+		if _, err := human.ParseDuration(hook.Backoff); err != nil {
+			return err
+		}
+		if _, err := hook.Client(); err != nil {
+			return err
+		}
+	}
+	return n.Email.Validate()
+}
+
+// Client returns webhook client of the settings. The template is read from the file, if it is not a built-in one
+func (w Config_Webhook) Client() (webhook.Client, error) {
+	backoff, _ := human.ParseDuration(w.Backoff)
+	client := webhook.Client{URL: w.URL, Template: w.Template, Retries: w.Retries, Backoff: backoff}
+	if _, builtin := webhook.Templates[w.Template]; !builtin && w.Template != "" && w.Template != "json" {
+		source, err := os.ReadFile(AbsPath(w.Template))
+		if err != nil {
+			return client, err
+		}
+		client.Template = string(source)
+	}
+	return client, nil
+}
+
+var changeTypeNames = map[ChangeType]string{ADDED: "added", MODIFIED: "modified", DELETED: "deleted"}
+
+// BuildPayload returns the run summary for notifications
+func BuildPayload(prev, curr SnapshotStruct, alerts []Alert) webhook.Payload {
+	host, _ := os.Hostname()
+	payload := webhook.Payload{
+		Host:      host,
+		Title:     ReportTitle(),
+		Time:      curr.StartTime,
+		Snapshot:  curr.ID,
+		Level:     MaxAlertLevel(alerts).String(),
+		FreeSpace: curr.FreeSpace,
+		FreeText:  HumanSize(curr.FreeSpace),
+		Dirs:      []webhook.Dir{},
+		Alerts:    []webhook.Alert{},
+		Changes:   []webhook.Change{},
+	}
+	for _, alert := range alerts {
+		payload.Alerts = append(payload.Alerts, webhook.Alert{Level: alert.Level.String(), Rule: alert.Rule, Message: alert.Message})
+	}
+	var changes []Change
+	for i, info := range curr.InfoList {
+		dir := webhook.Dir{Path: info.Path, Size: info.Size, Files: info.Files, SizeText: HumanSize(info.Size)}
+		if i < len(prev.InfoList) && prev.InfoList[i].Path != "" {
+			dir.DeltaSize = info.Size - prev.InfoList[i].Size
+			if dir.DeltaSize != 0 {
+				dir.DeltaText = HumanSizeSign(dir.DeltaSize)
+			}
+			for _, change := range Diff(prev.InfoList[i], info) {
+				if !change.File.IsDir {
+					changes = append(changes, change)
+				}
+			}
+		}
+		payload.Dirs = append(payload.Dirs, dir)
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return abs64(changes[i].Delta) > abs64(changes[j].Delta)
+	})
+	for i := 0; i < len(changes) && i < gCfg.Notify.TopChanges; i++ {
+		payload.Changes = append(payload.Changes, webhook.Change{
+			Path:      changes[i].Path,
+			Type:      changeTypeNames[changes[i].Type],
+			DeltaSize: changes[i].Delta,
+			DeltaText: HumanSizeSign(changes[i].Delta),
+		})
+	}
+	return payload
+}
+
+func abs64(v int64) int64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// AlertStateChanged returns true if the alert level differs from the level of the previous run. The level is saved
+// for the next run, unless the run is not saved (so a dry run doesn't suppress the next notification)
+func AlertStateChanged(level AlertLevel) bool {
+	file := gDataDir + "/alert-state"
+	prev, err := os.ReadFile(file)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		gLogger.Println(err)
+	}
+	if !*gNoSave {
+		if err := os.WriteFile(file, []byte(level.String()), 0666); err != nil {
+			LogErr(err)
+		}
+	}
+	return strings.TrimSpace(string(prev)) != level.String()
+}
+
+// Notify sends the run summary to the configured notifiers
+func Notify(prev, curr SnapshotStruct, alerts []Alert) {
+	if len(gCfg.Notify.Webhooks) == 0 && !gCfg.Notify.Email.Enabled() {
+		return
+	}
+	changed := AlertStateChanged(MaxAlertLevel(alerts))
+	skip := gCfg.Notify.OnStateChange && !changed
+	if gCfg.Notify.Email.Enabled() {
+		NotifyEmail(curr, alerts, skip)
+	}
+	if skip {
+		gLogger.Println("alert state is not changed, notifications skipped")
+		return
+	}
+	payload := BuildPayload(prev, curr, alerts)
+	for _, hook := range gCfg.Notify.Webhooks {
+		client, err := hook.Client()
+		start := time.Now()
+		if err == nil {
+			err = client.Send(payload)
+		}
+		if err != nil {
+			LogErr(err)
+			continue
+		}
+		gLogger.Println("webhook", hook.URL, "sent in", time.Since(start).Round(time.Millisecond))
+	}
+}
@@ -0,0 +1,8 @@
+package main
+
+import "os"
+
+// fileOwner is not supported on Windows: files have no uid/gid
+func fileOwner(fileInfo os.FileInfo) (uint32, uint32, bool) {
+	return 0, 0, false
+}
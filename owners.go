@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"os"
+	"os/user"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/owners"
+	"strconv"
+	"strings"
+)
+
+// Config_Owners settings of the breakdown by file owner
+type Config_Owners = owners.Config
+
+// resolved user and group names by id
+var gUserNames, gGroupNames = map[uint32]string{}, map[uint32]string{}
+
+// userName returns the name of the user (or the uid if the user is unknown)
+func userName(uid uint32) string {
+	name, ok := gUserNames[uid]
+	if !ok {
+		name = strconv.Itoa(int(uid))
+		if u, err := user.LookupId(name); err == nil {
+			name = u.Username
+		}
+		gUserNames[uid] = name
+	}
+	return name
+}
+
+// groupName returns the name of the group (or the gid if the group is unknown)
+func groupName(gid uint32) string {
+	name, ok := gGroupNames[gid]
+	if !ok {
+		name = strconv.Itoa(int(gid))
+		if g, err := user.LookupGroupId(name); err == nil {
+			name = g.Name
+		}
+		gGroupNames[gid] = name
+	}
+	return name
+}
+
+// addOwner counts the file in the owner breakdown of the directory (if enabled)
+func addOwner(info *DirInfoStruct, fileInfo os.FileInfo) {
+	if !gCfg.Owners.Enabled {
+		return
+	}
+	uid, gid, ok := fileOwner(fileInfo)
+	if !ok {
+		return
+	}
+	if info.Owners == nil {
+		info.Owners = map[string]UsageStat{}
+	}
+	addUsage(info.Owners, userName(uid), fileInfo.Size())
+	if gCfg.Owners.Groups {
+		if info.Groups == nil {
+			info.Groups = map[string]UsageStat{}
+		}
+		addUsage(info.Groups, groupName(gid), fileInfo.Size())
+	}
+}
+
+func byOwner(info DirInfoStruct) map[string]UsageStat { return info.Owners }
+func byGroup(info DirInfoStruct) map[string]UsageStat { return info.Groups }
+
+// ownerTotals returns usage of all users and groups ('@' prefixed) of the snapshot
+func ownerTotals(snapshot SnapshotStruct) map[string]UsageStat {
+	totals := SumBreakdown(snapshot, byOwner)
+	for name, stat := range SumBreakdown(snapshot, byGroup) {
+		totals["@"+name] = stat
+	}
+	return totals
+}
+
+// QuotaAlerts returns alerts of users and groups using more than the warning percent of their quota
+func QuotaAlerts(snapshot SnapshotStruct) []Alert {
+	if len(gCfg.Owners.Quotas) == 0 {
+		return nil
+	}
+	return gCfg.Owners.QuotaAlerts(ownerTotals(snapshot))
+}
+
+// PrintOwners prints sizes per user (and group) for each directory with deltas against the previous snapshot and quota usage
+func PrintOwners(prevSnapshot, currSnapshot SnapshotStruct) {
+	totals := ownerTotals(currSnapshot)
+	if len(totals) == 0 {
+		return
+	}
+	prevTotals := ownerTotals(prevSnapshot)
+
+	tableWriter := table.NewWriter()
+	tableWriter.SetTitle("by owner")
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	header := table.Row{"owner"}
+	perDir := len(currSnapshot.InfoList) > 1
+	if perDir {
+		for _, info := range currSnapshot.InfoList {
+			header = append(header, shorifyPath(info.Path))
+		}
+	}
+	header = append(header, "size", "files", "quota")
+	tableWriter.AppendHeader(header)
+
+	sizeWithDelta := func(size, prevSize int64, hasPrev bool) string {
+		if hasPrev && size != prevSize {
+			return HumanSize(size) + color.HiMagentaString(" "+HumanSizeSign(size-prevSize))
+		}
+		return HumanSize(size)
+	}
+	hasPrev := len(prevTotals) > 0
+	var users, groups []string
+	for _, name := range sortedKeys(totals) {
+		if strings.HasPrefix(name, "@") {
+			groups = append(groups, name)
+		} else {
+			users = append(users, name)
+		}
+	}
+	if len(groups) > 0 {
+		users = append(users, "") // separator
+	}
+	for _, name := range append(users, groups...) {
+		if name == "" {
+			tableWriter.AppendSeparator()
+			continue
+		}
+		stat, prevStat := totals[name], prevTotals[name]
+		row := table.Row{color.HiBlueString(name)}
+		if perDir {
+			for _, info := range currSnapshot.InfoList {
+				stats, breakdown := info.Owners, byOwner
+				if strings.HasPrefix(name, "@") {
+					stats, breakdown = info.Groups, byGroup
+				}
+				var prevSize int64
+				for _, prevInfo := range prevSnapshot.InfoList {
+					if prevInfo.Path == info.Path {
+						prevSize = breakdown(prevInfo)[strings.TrimPrefix(name, "@")].Size
+					}
+				}
+				row = append(row, sizeWithDelta(stats[strings.TrimPrefix(name, "@")].Size, prevSize, hasPrev))
+			}
+		}
+		files := fmt.Sprint(stat.Files)
+		if hasPrev && stat.Files != prevStat.Files {
+			files += fmt.Sprintf(" (%+d)", stat.Files-prevStat.Files)
+		}
+		quotaUsage := ""
+		if percent, level, ok := gCfg.Owners.QuotaUsage(name, stat.Size); ok {
+			quotaUsage = fmt.Sprintf("%.0f%% of %s", percent, gCfg.Owners.Quotas[name])
+			switch level {
+			case AlertCritical:
+				quotaUsage = color.HiRedString(quotaUsage)
+			case AlertWarning:
+				quotaUsage = color.HiYellowString(quotaUsage)
+			}
+		}
+		row = append(row, sizeWithDelta(stat.Size, prevStat.Size, hasPrev), files, quotaUsage)
+		tableWriter.AppendRow(row)
+	}
+	tableWriter.Render()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"github.com/mattn/go-isatty"
+	"io"
+	"os"
+	"space-monitor/libs/progress"
+	"space-monitor/libs/term"
+	"sync"
+	"time"
+)
+
+// interval of progress log lines when the output is not a terminal
+const progressLogInterval = 30 * time.Second
+
+// ScanProgress reports the scan in flight to the terminal or the log. It is safe for concurrent use
+type ScanProgress struct {
+	progress.Scan
+	mu    sync.Mutex // guards the terminal state
+	tty   io.Writer  // terminal with the drawn status line (nil if not reported to a terminal)
+	drawn bool
+}
+
+var gProgress = &ScanProgress{Scan: progress.Scan{ShortPath: shorifyPath}}
+
+// EndDir marks the end of the directory walk and clears the status line
+func (p *ScanProgress) EndDir() {
+	p.Scan.EndDir()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLine()
+}
+
+// Finish stops tracking the scan
+func (p *ScanProgress) Finish() {
+	p.Scan.Finish()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clearLine()
+}
+
+// draw redraws the status line on the terminal
+func (p *ScanProgress) draw() {
+	if p.tty == nil || !p.Walking() {
+		return
+	}
+	width, _, err := term.Size(int(os.Stderr.Fd()))
+	if err != nil || width <= 0 {
+		width = 80
+	}
+	fmt.Fprint(p.tty, "\r\x1b[K"+fitString(p.String(), width-1))
+	p.drawn = true
+}
+
+// clearLine erases the drawn status line
+func (p *ScanProgress) clearLine() {
+	if p.drawn {
+		fmt.Fprint(p.tty, "\r\x1b[K")
+		p.drawn = false
+	}
+}
+
+// Report shows the progress as a status line when stderr is a terminal or as periodic log lines otherwise.
+// Returns the function stopping the reporting
+func (p *ScanProgress) Report() (stop func()) {
+	interval := progressLogInterval
+	p.mu.Lock()
+	if isatty.IsTerminal(os.Stderr.Fd()) {
+		p.tty, interval = os.Stderr, 200*time.Millisecond
+	}
+	p.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				if p.tty != nil {
+					p.draw()
+				} else if p.Walking() {
+					gLogger.Println("progress:", p.String())
+				}
+				p.mu.Unlock()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		p.clearLine()
+		p.tty = nil
+	}
+}
+
+// ExpectedEntries returns the number of files and dirs of each directory of the snapshot (by path). The scan
+// progress of a directory is estimated by its entries in the previous snapshot
+func ExpectedEntries(snapshot SnapshotStruct) map[string]int {
+	expected := map[string]int{}
+	for _, info := range snapshot.InfoList {
+		if info.Path != "" {
+			expected[info.Path] = info.Files + info.Dirs
+		}
+	}
+	return expected
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/retention"
+	"strings"
+	"time"
+)
+
+// Config_Retention snapshot retention policy (grandfather-father-son)
+type Config_Retention = retention.Policy
+
+// EvaluateRetention applies the retention policy to all snapshots of the store
+func EvaluateRetention(policy Config_Retention) ([]retention.Decision, error) {
+	if !policy.HasKeepRules() {
+		policy.KeepLast = gCfg.MaxSnapshots // no keep-* rules: behave like plain max-snapshots
+	}
+	snapshots, err := ListSnapshots()
+	if err != nil {
+		return nil, err
+	}
+	return retention.Evaluate(policy, snapshots, time.Now(), gStore.Size)
+}
+
+// DeleteOldSnapshots deletes snapshots according to the retention policy
+func DeleteOldSnapshots() {
+	decisions, err := EvaluateRetention(gCfg.Retention)
+	if err != nil {
+		LogErr(err)
+		return
+	}
+	for _, d := range decisions {
+		if d.Keep {
+			continue
+		}
+		gLogger.Println("deleting snapshot", d.Snapshot.ID, "reason:", strings.Join(d.Reasons, "; "))
+		if err := gStore.Delete(d.Snapshot); err != nil {
+			LogErr(err)
+			return
+		}
+	}
+}
+
+// CommandPrune prints retention decisions for all snapshots and deletes the ones not kept (unless dry run)
+func CommandPrune(dryRun bool) int {
+	decisions, err := EvaluateRetention(gCfg.Retention)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	tableWriter := table.NewWriter()
+	if dryRun {
+		tableWriter.SetTitle("prune (dry run)")
+	}
+	tableWriter.SetStyle(table.StyleRounded)
+	tableWriter.SetOutputMirror(fmt2.OutWriter)
+	tableWriter.AppendHeader(table.Row{"snapshot", "age", "action", "reason"})
+	removed := 0
+	for i := len(decisions) - 1; i >= 0; i-- { // older first
+		d := decisions[i]
+		action := color.HiGreenString("keep")
+		if !d.Keep {
+			removed++
+			action = color.HiRedString("remove")
+			if !dryRun {
+				if err := gStore.Delete(d.Snapshot); err != nil {
+					LogErr(err)
+					return 1
+				}
+				gLogger.Println("pruned snapshot", d.Snapshot.ID, "reason:", strings.Join(d.Reasons, "; "))
+			}
+		}
+		tableWriter.AppendRow(table.Row{
+			d.Snapshot.ID,
+			TimeAgo(d.Snapshot.StartTime),
+			action,
+			strings.Join(d.Reasons, "; "),
+		})
+	}
+	tableWriter.Render()
+	if dryRun {
+		fmt2.Printf("%d of %d snapshots would be removed\n", removed, len(decisions))
+	} else {
+		fmt2.Printf("%d of %d snapshots removed\n", removed, len(decisions))
+	}
+	return 0
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"space-monitor/libs/store"
+	"strings"
+)
+
+// The snapshot history types live in libs/store, so the stores can be tested and implemented outside of the main package
+type (
+	SnapshotStore  = store.SnapshotStore
+	SnapshotStruct = store.Snapshot
+	DirInfoStruct  = store.DirInfo
+	GobFileInfo    = store.FileInfo
+	MountUsage     = store.MountUsage
+	UsageStat      = store.UsageStat
+	StaleSubtree   = store.StaleSubtree
+)
+
+// StoreFactory opens a snapshot store for the application config
+type StoreFactory func(cfg Config) (SnapshotStore, error)
+
+var gStoreFactories = map[string]StoreFactory{}
+
+// RegisterStore makes the snapshot store available by the name for the 'storage' config option.
+// Alternative stores (implementing store.SnapshotStore) are plugged in by calling it from an init() function
+func RegisterStore(name string, factory StoreFactory) {
+	gStoreFactories[name] = factory
+}
+
+func init() {
+	RegisterStore("folder", func(cfg Config) (SnapshotStore, error) {
+		return store.NewFolderStore(gDataDir, cfg.Compression)
+	})
+	RegisterStore("bolt", func(cfg Config) (SnapshotStore, error) {
+		return store.OpenBoltStore(gDataDir+"/history.db", gDataDir, cfg.Compression)
+	})
+	RegisterStore("memory", func(cfg Config) (SnapshotStore, error) {
+		return store.NewMemoryStore(), nil
+	})
+}
+
+// OpenStore opens snapshot store configured by the 'storage' config option (folder by default)
+func OpenStore(cfg Config) (SnapshotStore, error) {
+	name := cfg.Storage
+	if name == "" {
+		name = "folder"
+	}
+	factory, ok := gStoreFactories[name]
+	if !ok {
+		var names []string
+		for name := range gStoreFactories {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("unknown storage: %q (%s expected)", cfg.Storage, strings.Join(names, ", "))
+	}
+	return factory(cfg)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"space-monitor/libs/age"
+	"space-monitor/libs/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAgeBucketOf(t *testing.T) {
+	tests := map[time.Duration]string{
+		-time.Hour:                "< 1 day",
+		time.Hour:                 "< 1 day",
+		24 * time.Hour:            "< 1 week",
+		40 * 24 * time.Hour:       "< 3 months",
+		400 * 24 * time.Hour:      "< 2 years",
+		10 * 365 * 24 * time.Hour: ">= 5 years",
+	}
+	for d, want := range tests {
+		if got := age.BucketOf(d); got != want {
+			t.Errorf("BucketOf(%v) = %q; want %q", d, got, want)
+		}
+	}
+}
+
+func TestStaleTracker(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	old, recent := now.AddDate(-2, 0, 0), now.AddDate(0, 0, -1)
+	root := t.TempDir()
+	files := []struct {
+		path  string
+		size  int
+		mtime time.Time
+	}{
+		{path: "a/x", size: 100, mtime: old},
+		{path: "a/y", size: 50, mtime: old.AddDate(0, 1, 0)},
+		{path: "b/c/old", size: 300, mtime: old},
+		{path: "b/d/old", size: 30, mtime: old},
+		{path: "b/new", size: 10, mtime: recent},
+		{path: "e/f", size: 1000, mtime: recent},
+		{path: "g/h/i", size: 5, mtime: old},
+	}
+	for _, file := range files {
+		path := filepath.Join(root, file.path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Repeat("x", file.size)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, file.mtime, file.mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	walk := func(root string, cutoff time.Time, top int) []store.StaleSubtree {
+		tracker := age.NewStaleTracker(root, cutoff, top)
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err == nil && !info.IsDir() {
+				tracker.Add(path, info.Size(), info.ModTime())
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tracker.Finish()
+	}
+	equal := func(got, want []store.StaleSubtree) bool {
+		if len(got) != len(want) {
+			return false
+		}
+		for i := range want {
+			if got[i].Path != want[i].Path || got[i].Size != want[i].Size || got[i].Files != want[i].Files || !got[i].Newest.Equal(want[i].Newest) {
+				return false
+			}
+		}
+		return true
+	}
+
+	cutoff := now.AddDate(-1, 0, 0)
+	want := []store.StaleSubtree{
+		{Path: filepath.Join(root, "b/c"), Size: 300, Files: 1, Newest: old},
+		{Path: filepath.Join(root, "a"), Size: 150, Files: 2, Newest: old.AddDate(0, 1, 0)}, // topmost only, not a/x
+		{Path: filepath.Join(root, "b/d"), Size: 30, Files: 1, Newest: old},
+		{Path: filepath.Join(root, "g"), Size: 5, Files: 1, Newest: old},
+	}
+	if got := walk(root, cutoff, 10); !equal(got, want) {
+		t.Errorf("stale subtrees:\n%v\nwant\n%v", got, want)
+	}
+	if got := walk(root, cutoff, 2); !equal(got, want[:2]) {
+		t.Errorf("top 2 stale subtrees: %v", got)
+	}
+
+	// everything is stale: the root itself
+	stale := []store.StaleSubtree{{Path: filepath.Join(root, "b"), Size: 340, Files: 3, Newest: recent}}
+	if got := walk(filepath.Join(root, "b"), now, 10); !equal(got, stale) {
+		t.Errorf("stale root: %v", got)
+	}
+}
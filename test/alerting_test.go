@@ -0,0 +1,153 @@
+package main
+
+import (
+	"space-monitor/libs/alerting"
+	"space-monitor/libs/store"
+	"strings"
+	"testing"
+)
+
+func TestParseThreshold(t *testing.T) {
+	tests := []struct {
+		str  string
+		size bool
+		want alerting.Threshold
+	}{
+		{str: "5%", size: true, want: alerting.Threshold{Value: 5, Percent: true}},
+		{str: " 12.5% ", size: false, want: alerting.Threshold{Value: 12.5, Percent: true}},
+		{str: "10G", size: true, want: alerting.Threshold{Value: 10 * 1024 * 1024 * 1024}},
+		{str: "1000", size: true, want: alerting.Threshold{Value: 1000}},
+		{str: "1000", size: false, want: alerting.Threshold{Value: 1000}},
+	}
+	for _, test := range tests {
+		if got, err := alerting.ParseThreshold(test.str, test.size); err != nil || got != test.want {
+			t.Errorf("ParseThreshold(%q, %v) = %v, %v; want %v", test.str, test.size, got, err, test.want)
+		}
+	}
+	for _, str := range []string{"", "101%", "-1%", "x%", "10G%"} {
+		if _, err := alerting.ParseThreshold(str, true); err == nil {
+			t.Errorf("ParseThreshold(%q): error expected", str)
+		}
+	}
+	if _, err := alerting.ParseThreshold("10G", false); err == nil {
+		t.Errorf("ParseThreshold(10G) of a count: error expected")
+	}
+
+	if limit := (alerting.Threshold{Value: 10, Percent: true}).Limit(500); limit != 50 {
+		t.Errorf("10%% of 500 = %v", limit)
+	}
+	if limit := (alerting.Threshold{Value: 10}).Limit(500); limit != 10 {
+		t.Errorf("absolute limit = %v", limit)
+	}
+}
+
+func TestAlertRuleValidate(t *testing.T) {
+	valid := []alerting.Rule{
+		{FreeBelow: "5%"},
+		{Level: "critical", Path: "/var", SizeAbove: "50G", GrowthPerDayAbove: "1G"},
+		{InodesFreeBelow: "1000"},
+	}
+	for _, rule := range valid {
+		if err := rule.Validate(); err != nil {
+			t.Errorf("%+v: %v", rule, err)
+		}
+	}
+	invalid := []alerting.Rule{
+		{Level: "fatal", FreeBelow: "5%"},
+		{FreeBelow: "5 apples"},
+		{InodesFreeBelow: "1G"},
+		{SizeAbove: "50G"}, // requires path
+		{Path: "/var", GrowthPerRunAbove: "a lot"},
+	}
+	for _, rule := range invalid {
+		if err := rule.Validate(); err == nil {
+			t.Errorf("%+v: error expected", rule)
+		}
+	}
+}
+
+func TestAlertEvaluate(t *testing.T) {
+	const G = 1024 * 1024 * 1024
+	prev := store.Snapshot{
+		FreeSpace: 30 * G,
+		InfoList:  []store.DirInfo{{Path: "/home/docs", Size: 5 * G}, {Path: "/var", Size: 20 * G}},
+	}
+	curr := store.Snapshot{
+		FreeSpace: 20 * G,
+		Mounts: []store.MountUsage{
+			{Path: "/", Total: 100 * G, Free: 20 * G, InodesTotal: 1000, InodesFree: 50},
+			{Path: "/home", Total: 200 * G, Free: 4 * G},
+		},
+		// the directories are reordered in the config since the previous run
+		InfoList: []store.DirInfo{{Path: "/var", Size: 22 * G}, {Path: "/home/docs", Size: 9 * G}},
+	}
+	rates := alerting.Rates{Dirs: map[string]float64{"/var": 3 * G}, Used: 1 * G, UsedValid: true}
+	absPath := func(path string) string { return strings.Replace(path, "~", "/home", 1) }
+
+	tests := []struct {
+		name string
+		rule alerting.Rule
+		want []string // "LEVEL message" of the raised alerts
+	}{
+		{name: "free percent of all mounts", rule: alerting.Rule{FreeBelow: "5%"},
+			want: []string{"WARNING free space of /home 4.0G is below 5%"}},
+		{name: "free of the path mount", rule: alerting.Rule{Level: "critical", Path: "/var", FreeBelow: "25G"},
+			want: []string{"CRITICAL free space of / 20.0G is below 25G"}},
+		{name: "free ok", rule: alerting.Rule{Path: "/var", FreeBelow: "10%"}},
+		{name: "inodes", rule: alerting.Rule{InodesFreeBelow: "10%"},
+			want: []string{"WARNING free inodes of / 50 are below 10%"}},
+		{name: "size", rule: alerting.Rule{Path: "~/docs", SizeAbove: "8G"},
+			want: []string{"WARNING size of ~/docs 9.0G is above 8G"}},
+		{name: "growth per run is matched by path", rule: alerting.Rule{Path: "~/docs", GrowthPerRunAbove: "3G"},
+			want: []string{"WARNING ~/docs grew by 4.0G since the previous run (above 3G)"}},
+		{name: "growth per run below", rule: alerting.Rule{Path: "/var", GrowthPerRunAbove: "3G"}},
+		{name: "growth per day", rule: alerting.Rule{Path: "/var", GrowthPerDayAbove: "2G"},
+			want: []string{"WARNING /var grows by +3.0G per day (above 2G)"}},
+		{name: "growth per day without samples", rule: alerting.Rule{Path: "~/docs", GrowthPerDayAbove: "1K"}},
+		{name: "used space growth", rule: alerting.Rule{GrowthPerRunAbove: "5G", GrowthPerDayAbove: "512M"},
+			want: []string{"WARNING used space grew by 10.0G since the previous run (above 5G)", "WARNING used space grows by +1.0G per day (above 512M)"}},
+		{name: "not configured directory", rule: alerting.Rule{Path: "/tmp", SizeAbove: "1G"},
+			want: []string{"WARNING /tmp is not a configured directory"}},
+	}
+	for _, test := range tests {
+		alerts := alerting.Evaluate([]alerting.Rule{test.rule}, prev, curr, rates, absPath)
+		var got []string
+		for _, alert := range alerts {
+			got = append(got, alert.Level.String()+" "+alert.Message)
+		}
+		if strings.Join(got, "\n") != strings.Join(test.want, "\n") {
+			t.Errorf("%s: got %q; want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAlertEvaluateWithoutMounts(t *testing.T) {
+	// snapshots of older versions have no mount usage
+	curr := store.Snapshot{FreeSpace: 1024}
+	identity := func(path string) string { return path }
+	rules := []alerting.Rule{{Name: "absolute", FreeBelow: "2K"}, {Name: "percent", Level: "critical", FreeBelow: "5%"}}
+	alerts := alerting.Evaluate(rules, store.Snapshot{}, curr, alerting.Rates{}, identity)
+	if len(alerts) != 2 {
+		t.Fatalf("got %d alerts; want 2: %v", len(alerts), alerts)
+	}
+	if alerts[0].Level != alerting.Warning || alerts[0].Message != "free space 1.0K is below 2K" {
+		t.Errorf("absolute: %v", alerts[0])
+	}
+	if alerts[1].Level != alerting.Unknown || alerts[1].Rule != "percent" {
+		t.Errorf("percent rule without mounts: %v; UNKNOWN expected", alerts[1])
+	}
+	// the free space of the snapshot isn't of the path filesystem
+	for _, snapshot := range []store.Snapshot{curr, {FreeSpace: 1024, Mounts: []store.MountUsage{{Path: "/home", Total: 4096, Free: 1024}}}} {
+		rules := []alerting.Rule{{Path: "/mnt/usb", FreeBelow: "2K", InodesFreeBelow: "10%"}}
+		alerts := alerting.Evaluate(rules, store.Snapshot{}, snapshot, alerting.Rates{}, identity)
+		if len(alerts) != 1 || alerts[0].Level != alerting.Unknown || alerts[0].Message != "no mount for /mnt/usb" {
+			t.Errorf("path rule without its mount: %v; UNKNOWN expected", alerts)
+		}
+	}
+	if level := alerting.MaxLevel(alerts); level != alerting.Unknown || int(level) != 3 {
+		t.Errorf("MaxLevel = %v", level)
+	}
+	if level := alerting.MaxLevel(nil); level != alerting.OK {
+		t.Errorf("MaxLevel of no alerts = %v", level)
+	}
+}
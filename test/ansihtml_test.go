@@ -0,0 +1,25 @@
+package main
+
+import (
+	"space-monitor/libs/ansihtml"
+	"testing"
+)
+
+func TestAnsiHtml(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"plain <text>", "plain &lt;text&gt;"},
+		{"\x1b[94m/var\x1b[0m 1.5G", `<span style="color:#3b8eea">/var</span> 1.5G`},
+		{"\x1b[1;91mCRITICAL\x1b[0m", `<span style="color:#f14c4c;font-weight:bold">CRITICAL</span>`},
+		{"\x1b[95m\x1b[0m", ""},
+		{"\x1b[2Kline\x1b[3", "line"},
+		{"\x1b[38;5;208mx\x1b[m", "x"},
+	}
+	for _, test := range tests {
+		if result := ansihtml.Convert(test.input); result != test.expected {
+			t.Errorf("Convert(%q) = %q; want %q", test.input, result, test.expected)
+		}
+	}
+}
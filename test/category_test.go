@@ -0,0 +1,74 @@
+package main
+
+import (
+	"reflect"
+	"space-monitor/libs/category"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestCategoryMatch(t *testing.T) {
+	matcher, err := category.New(map[string][]string{
+		"logs":  {"log", "*.log.*"},
+		"video": {".MP4", "mkv"},
+		"zip":   {"gz"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := map[string]string{
+		"movie.mp4":     "video",
+		"Movie.MKV":     "video",
+		"app.log":       "logs",
+		"app.log.1":     "logs",
+		"app.log.2.gz":  "logs", // patterns first
+		"backup.tar.gz": "zip",
+		"readme":        "",
+		"photo.jpg":     "",
+		".log":          "",
+	}
+	for name, want := range tests {
+		if got := matcher.Match(name); got != want {
+			t.Errorf("Match(%q) = %q; want %q", name, got, want)
+		}
+	}
+	if _, err := category.New(map[string][]string{"bad": {"[*.log"}}); err == nil {
+		t.Errorf("expected invalid pattern error")
+	}
+}
+
+func TestExtension(t *testing.T) {
+	tests := map[string]string{
+		"a.TXT":                "txt",
+		"archive.tar.gz":       "gz",
+		"Makefile":             category.NoExtension,
+		".bashrc":              category.NoExtension,
+		"dump.20230101T120000": category.NoExtension,
+	}
+	for name, want := range tests {
+		if got := category.Extension(name); got != want {
+			t.Errorf("Extension(%q) = %q; want %q", name, got, want)
+		}
+	}
+}
+
+func TestTopExtensions(t *testing.T) {
+	stats := map[string]store.UsageStat{
+		"mkv":                    {Size: 1000, Files: 2},
+		"log":                    {Size: 500, Files: 10},
+		"tmp":                    {Size: 20, Files: 4},
+		"a1b2":                   {Size: 10, Files: 1},
+		category.OtherExtensions: {Size: 5, Files: 1}, // already summed (eg. merged breakdowns)
+	}
+	want := map[string]store.UsageStat{
+		"mkv":                    {Size: 1000, Files: 2},
+		"log":                    {Size: 500, Files: 10},
+		category.OtherExtensions: {Size: 35, Files: 6},
+	}
+	if got := category.Top(stats, 2); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top(2) = %v; want %v", got, want)
+	}
+	if got := category.Top(stats, 10); !reflect.DeepEqual(got, stats) {
+		t.Errorf("Top(10) = %v; want all", got)
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"space-monitor/libs/alerting"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestCheckOutput(t *testing.T) {
+	const G = 1024 * 1024 * 1024
+	snapshot := store.Snapshot{
+		FreeSpace: 40 * G,
+		Mounts: []store.MountUsage{
+			{Path: "/", Total: 100 * G, Free: 40 * G},
+			{Path: "/home", Total: 200 * G, Free: 150 * G},
+		},
+		InfoList: []store.DirInfo{{Path: "/home/it's", Size: 1000}, {}, {Path: "/var", Size: 2 * G}},
+	}
+	rules := []alerting.Rule{
+		{FreeBelow: "10%"}, // all mounts
+		{Level: "critical", Path: "/home/x", FreeBelow: "5G"}, // the mount of the path
+		{Level: "critical", Path: "/var", SizeAbove: "3G"},
+		{Path: "/var", SizeAbove: "1K"},
+	}
+	identity := func(path string) string { return path }
+
+	want := "SPACE-MONITOR OK - 40.0G free on / (40%); 150.0G free on /home (75%) | " +
+		"'free /'=42949672960B;10737418240;;0;107374182400 " +
+		"'free /home'=161061273600B;21474836480;5368709120;0;214748364800 " +
+		"'/home/it''s'=1000B;;;0; " +
+		"'/var'=2147483648B;1024;3221225472;0;"
+	if got := alerting.CheckOutput(alerting.OK, nil, rules, snapshot, identity); got != want {
+		t.Errorf("CheckOutput =\n%s\nwant\n%s", got, want)
+	}
+
+	alerts := []alerting.Alert{
+		{Level: alerting.Warning, Rule: "/var", Message: "size of /var 2.0G is above 1K"},
+		{Level: alerting.Critical, Rule: "x", Message: "something else"},
+	}
+	want = "SPACE-MONITOR CRITICAL - size of /var 2.0G is above 1K; something else | 'free'=1024B;;;0;"
+	if got := alerting.CheckOutput(alerting.Critical, alerts, nil, store.Snapshot{FreeSpace: 1024}, identity); got != want {
+		t.Errorf("CheckOutput without mounts =\n%s\nwant\n%s", got, want)
+	}
+
+	want = "SPACE-MONITOR OK - 1.0K free | 'free'=1024B;;;0;"
+	if got := alerting.CheckOutput(alerting.OK, nil, []alerting.Rule{{FreeBelow: "5%"}}, store.Snapshot{FreeSpace: 1024}, identity); got != want {
+		t.Errorf("percent threshold without mount total =\n%s\nwant\n%s", got, want)
+	}
+
+	// zero sized mounts (eg. pseudo filesystems) would give NaN percents
+	zero := store.Snapshot{FreeSpace: 1024, Mounts: []store.MountUsage{{Path: "/proc"}}}
+	want = "SPACE-MONITOR OK - 1.0K free | 'free'=1024B;;;0;"
+	if got := alerting.CheckOutput(alerting.OK, nil, nil, zero, identity); got != want {
+		t.Errorf("zero sized mount =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCheckOutputOverlappingRules(t *testing.T) {
+	const G = 1024 * 1024 * 1024
+	snapshot := store.Snapshot{
+		Mounts:   []store.MountUsage{{Path: "/", Total: 100 * G, Free: 40 * G}},
+		InfoList: []store.DirInfo{{Path: "/var", Size: 2 * G}},
+	}
+	// the strictest limits win regardless of the rule order
+	rules := []alerting.Rule{
+		{FreeBelow: "20G"},
+		{Path: "/var", FreeBelow: "30%"},
+		{FreeBelow: "10%"},
+		{Level: "critical", FreeBelow: "5G"},
+		{Level: "critical", Path: "/var", FreeBelow: "8G"},
+		{Path: "/var", SizeAbove: "3G"},
+		{Path: "/var", SizeAbove: "1G"},
+		{Level: "critical", Path: "/var", SizeAbove: "10G"},
+		{Level: "critical", Path: "/var", SizeAbove: "5G"},
+	}
+	identity := func(path string) string { return path }
+	want := "SPACE-MONITOR OK - 40.0G free on / (40%) | " +
+		"'free /'=42949672960B;32212254720;8589934592;0;107374182400 " +
+		"'/var'=2147483648B;1073741824;5368709120;0;"
+	for _, order := range [][]alerting.Rule{rules, {rules[8], rules[7], rules[6], rules[5], rules[4], rules[3], rules[2], rules[1], rules[0]}} {
+		if got := alerting.CheckOutput(alerting.OK, nil, order, snapshot, identity); got != want {
+			t.Errorf("CheckOutput =\n%s\nwant\n%s", got, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"reflect"
+	"space-monitor/libs/diff"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestDiffFiles(t *testing.T) {
+	prev := map[string]store.FileInfo{
+		"/etc":            {IsDir: true, Size: 1100},
+		"/etc/hosts":      {Size: 100, Hash: []byte{1, 2}},
+		"/etc/passwd":     {Size: 500, Hash: []byte{3, 4}},
+		"/etc/big.img":    {Size: 500}, // above hash-max-size
+		"/etc/resolv.old": {Size: 0, Hash: []byte{5}},
+	}
+	curr := map[string]store.FileInfo{
+		"/etc":         {IsDir: true, Size: 1220},
+		"/etc/hosts":   {Size: 100, Hash: []byte{1, 3}}, // same size, different content
+		"/etc/passwd":  {Size: 520, Hash: []byte{3, 5}},
+		"/etc/big.img": {Size: 500},
+		"/etc/group":   {Size: 100, Hash: []byte{6}},
+	}
+	want := []diff.Change{
+		{Path: "/etc", Type: diff.Modified, File: curr["/etc"], Delta: 120},
+		{Path: "/etc/group", Type: diff.Added, File: curr["/etc/group"], Delta: 100},
+		{Path: "/etc/hosts", Type: diff.Modified, File: curr["/etc/hosts"], Delta: 0},
+		{Path: "/etc/passwd", Type: diff.Modified, File: curr["/etc/passwd"], Delta: 20},
+		{Path: "/etc/resolv.old", Type: diff.Deleted, File: prev["/etc/resolv.old"], Delta: 0},
+	}
+	changes := diff.Files(prev, curr)
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes; want %d: %+v", len(changes), len(want), changes)
+	}
+	for i := range want {
+		if changes[i].Path != want[i].Path || changes[i].Type != want[i].Type || changes[i].Delta != want[i].Delta ||
+			changes[i].File.Size != want[i].File.Size || string(changes[i].File.Hash) != string(want[i].File.Hash) {
+			t.Errorf("change %d = %+v; want %+v", i, changes[i], want[i])
+		}
+	}
+
+	// a digest missing on either side can't detect a content change (eg. hashing enabled later)
+	prev["/etc/hosts"] = store.FileInfo{Size: 100}
+	for _, change := range diff.Files(prev, curr) {
+		if change.Path == "/etc/hosts" {
+			t.Errorf("file without previous digest is reported: %+v", change)
+		}
+	}
+
+	if changes := diff.Files(nil, curr); len(changes) != 0 {
+		t.Errorf("first run: got %d changes", len(changes))
+	}
+}
+
+func TestDiffChildren(t *testing.T) {
+	prev := map[string]store.FileInfo{
+		"/d":        {IsDir: true, Size: 350},
+		"/d/same":   {Size: 100},
+		"/d/grown":  {Size: 50},
+		"/d/gone":   {Size: 200},
+		"/d/sub":    {IsDir: true},
+		"/d/sub/ok": {},
+	}
+	curr := map[string]store.FileInfo{
+		"/d":       {IsDir: true, Size: 460},
+		"/d/same":  {Size: 100},
+		"/d/grown": {Size: 300},
+		"/d/new":   {Size: 60},
+		"/d/sub":   {IsDir: true},
+	}
+	entries := diff.Children(curr, prev, diff.Index(curr, "/d"), diff.Index(prev, "/d"), "/d", true)
+	diff.SortEntries(entries, false)
+	want := []diff.Entry{
+		{Path: "/d/grown", Size: 300, Delta: 250},
+		{Path: "/d/same", Size: 100},
+		{Path: "/d/new", Size: 60, Delta: 60, Added: true},
+		{Path: "/d/gone", Delta: -200, Deleted: true}, // same size, then by path
+		{Path: "/d/sub", IsDir: true},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("by size:\n%+v\nwant\n%+v", entries, want)
+	}
+
+	diff.SortEntries(entries, true)
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.Path)
+	}
+	if want := []string{"/d/grown", "/d/gone", "/d/new", "/d/same", "/d/sub"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("by delta = %v; want %v", paths, want)
+	}
+
+	// nothing to compare with: no added or deleted entries
+	entries = diff.Children(curr, nil, diff.Index(curr, "/d"), nil, "/d", false)
+	for _, entry := range entries {
+		if entry.Added || entry.Deleted || entry.Delta != 0 {
+			t.Errorf("entry without comparison: %+v", entry)
+		}
+	}
+	if len(entries) != 4 {
+		t.Errorf("got %d entries; want 4", len(entries))
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"space-monitor/libs/du"
+	"space-monitor/libs/store"
+	"strings"
+	"testing"
+)
+
+func TestDuRead(t *testing.T) {
+	entries, err := du.Read(strings.NewReader("5\t./a/f1\n\n10\t./a/with\ttab\n4111\t./a\r\n8207\t.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []du.Entry{{Path: "./a/f1", Size: 5}, {Path: "./a/with\ttab", Size: 10}, {Path: "./a", Size: 4111}, {Path: ".", Size: 8207}}
+	if len(entries) != len(want) {
+		t.Fatalf("got %v; want %v", entries, want)
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %v; want %v", i, entries[i], want[i])
+		}
+	}
+	if _, err := du.Read(strings.NewReader("4.0K\t./a\n")); err == nil {
+		t.Errorf("expected error for human readable sizes")
+	}
+}
+
+func TestDuReadFileMap(t *testing.T) {
+	dump := "5\t/data/a/f1\n10\t/data/a/f2\n4111\t/data/a\n4096\t/data/empty\n100\t/data/top.txt\n8307\t/data/\n"
+	root, fileMap, err := du.ReadFileMap(strings.NewReader(dump))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if root != "/data" {
+		t.Errorf("root = %q; want /data (the last line)", root)
+	}
+	want := map[string]store.FileInfo{
+		"/data":         {IsDir: true},
+		"/data/a":       {IsDir: true},
+		"/data/a/f1":    {Size: 5},
+		"/data/a/f2":    {Size: 10},
+		"/data/empty":   {Size: 4096}, // du doesn't tell empty directories from files
+		"/data/top.txt": {Size: 100},
+	}
+	if !reflect.DeepEqual(fileMap, want) {
+		t.Errorf("file map = %v; want %v", fileMap, want)
+	}
+	if _, _, err := du.ReadFileMap(strings.NewReader("\n")); err == nil {
+		t.Errorf("empty output: error expected")
+	}
+}
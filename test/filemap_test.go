@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"reflect"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func testFileMap() map[string]store.FileInfo {
+	return map[string]store.FileInfo{
+		"/home/user":                   {IsDir: true, Size: 1536},
+		"/home/user/docs":              {IsDir: true, Size: 1024},
+		"/home/user/docs/report.pdf":   {Size: 1000, Hash: []byte{1, 2, 3, 4}},
+		"/home/user/docs/report-2.pdf": {Size: 24},
+		"/home/user/music.mp3":         {Size: 512},
+		"/home/user/empty":             {IsDir: true},
+	}
+}
+
+func TestFileMapRoundTrip(t *testing.T) {
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		var buffer bytes.Buffer
+		ext, err := store.EncodeFileMap(&buffer, testFileMap(), compression)
+		if err != nil {
+			t.Fatalf("%s: %v", compression, err)
+		}
+		if want, _ := store.FileMapExt(compression); ext != want {
+			t.Errorf("%s: extension %q; want %q", compression, ext, want)
+		}
+		fileMap, err := store.ReadFileMapFormat(&buffer, ext)
+		if err != nil {
+			t.Fatalf("%s: %v", compression, err)
+		}
+		if !reflect.DeepEqual(fileMap, testFileMap()) {
+			t.Errorf("%s: decoded %v", compression, fileMap)
+		}
+	}
+	if _, err := store.FileMapExt("lz4"); err == nil {
+		t.Errorf("unknown compression: error expected")
+	}
+}
+
+func TestLoadFileMap(t *testing.T) {
+	dir := t.TempDir()
+	for _, compression := range []string{"none", "gzip", "zstd"} {
+		base := filepath.Join(dir, compression)
+		if err := store.SaveFileMap(base, testFileMap(), compression); err != nil {
+			t.Fatal(err)
+		}
+		fileMap, err := store.LoadFileMap(base)
+		if err != nil || !reflect.DeepEqual(fileMap, testFileMap()) {
+			t.Errorf("%s: %v, %v", compression, fileMap, err)
+		}
+	}
+
+	// legacy raw gob map written by the first versions
+	base := filepath.Join(dir, "legacy")
+	file, err := os.Create(base + store.FileMapExtLegacy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = gob.NewEncoder(file).Encode(testFileMap())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileMap, err := store.LoadFileMap(base)
+	if err != nil || !reflect.DeepEqual(fileMap, testFileMap()) {
+		t.Errorf("legacy: %v, %v", fileMap, err)
+	}
+
+	if _, err := store.LoadFileMap(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("missing file map: error expected")
+	}
+}
+
+func TestReadFileMapCorrupted(t *testing.T) {
+	// the header promises a huge number of entries, but the stream ends
+	var buffer bytes.Buffer
+	if err := gob.NewEncoder(&buffer).Encode(struct{ Count int }{Count: 1 << 40}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReadFileMap(&buffer); err == nil {
+		t.Errorf("truncated file map: error expected")
+	}
+
+	buffer.Reset()
+	if err := gob.NewEncoder(&buffer).Encode(struct{ Count int }{Count: -1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReadFileMap(&buffer); err == nil {
+		t.Errorf("negative entry count: error expected")
+	}
+
+	// the entry shares a longer prefix than the previous path has
+	buffer.Reset()
+	encoder := gob.NewEncoder(&buffer)
+	_ = encoder.Encode(struct{ Count int }{Count: 1})
+	_ = encoder.Encode(struct {
+		Prefix int
+		Suffix string
+	}{Prefix: 5, Suffix: "/a"})
+	if _, err := store.ReadFileMap(&buffer); err == nil {
+		t.Errorf("corrupted prefix: error expected")
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"space-monitor/libs/find"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestPathMatcher(t *testing.T) {
+	tests := []struct {
+		pattern string
+		regex   bool
+		path    string
+		want    bool
+	}{
+		{pattern: "*.iso", path: "/home/user/Downloads/ubuntu.iso", want: true}, // base name
+		{pattern: "*.iso", path: "/home/user/iso.d/readme", want: false},
+		{pattern: "ubuntu*", path: "/srv/ubuntu/file", want: false},
+		{pattern: "/home/*/Downloads/*.iso", path: "/home/user/Downloads/ubuntu.iso", want: true}, // full path
+		{pattern: "/home/*/*.iso", path: "/home/user/Downloads/ubuntu.iso", want: false},
+		{pattern: "Downloads/*.iso", path: "/home/user/Downloads/ubuntu.iso", want: false},
+		{pattern: `\.(iso|img)$`, regex: true, path: "/var/lib/disk.img", want: true},
+		{pattern: `^/var/log/`, regex: true, path: "/home/var/log/x", want: false},
+		{pattern: `cache`, regex: true, path: "/home/user/.cache/thumbnails", want: true},
+	}
+	for _, test := range tests {
+		match, err := find.PathMatcher(test.pattern, test.regex)
+		if err != nil {
+			t.Errorf("%q: %v", test.pattern, err)
+			continue
+		}
+		if got := match(test.path); got != test.want {
+			t.Errorf("%q matches %q = %v; want %v", test.pattern, test.path, got, test.want)
+		}
+	}
+	if _, err := find.PathMatcher("[*.iso", false); err == nil {
+		t.Errorf("bad glob: error expected")
+	}
+	if _, err := find.PathMatcher("(iso", true); err == nil {
+		t.Errorf("bad regex: error expected")
+	}
+}
+
+func TestFinder(t *testing.T) {
+	const M = 1024 * 1024
+	snapshots := []map[string]store.FileInfo{
+		{
+			"/data":           {IsDir: true, Size: 150 * M},
+			"/data/old.log":   {Size: 100 * M}, // deleted later
+			"/data/small.log": {Size: 50 * M},
+		},
+		{
+			"/data":           {IsDir: true, Size: 250 * M},
+			"/data/small.log": {Size: 200 * M}, // in the window only here
+			"/data/new.log":   {Size: 50 * M},
+			"/data/b.log":     {Size: 50 * M},
+		},
+		{
+			"/data":           {IsDir: true, Size: 55 * M},
+			"/data/small.log": {Size: 5 * M},
+			"/data/new.log":   {Size: 50 * M},
+			"/data/b.log":     {Size: 5 * M},
+		},
+	}
+	match, _ := find.PathMatcher("*.log", false)
+	finder := find.NewFinder(match)
+	for i, fileMap := range snapshots {
+		finder.Add(i, fileMap)
+	}
+
+	// recently appeared first, then by path
+	result := finder.Result(0, 0)
+	want := []struct {
+		path        string
+		first, last int
+		sizes       int
+	}{
+		{path: "/data/b.log", first: 1, last: 2, sizes: 2},
+		{path: "/data/new.log", first: 1, last: 2, sizes: 2},
+		{path: "/data/old.log", first: 0, last: 0, sizes: 1},
+		{path: "/data/small.log", first: 0, last: 2, sizes: 3},
+	}
+	if len(result) != len(want) {
+		t.Fatalf("got %d matches; want %d", len(result), len(want))
+	}
+	for i, m := range result {
+		if m.Path != want[i].path || m.First != want[i].first || m.Last != want[i].last || len(m.Sizes) != want[i].sizes || m.IsDir {
+			t.Errorf("match %d = %+v; want %+v", i, *m, want[i])
+		}
+	}
+
+	// the size is within the window in any snapshot
+	paths := func(matches []*find.Match) []string {
+		var paths []string
+		for _, m := range matches {
+			paths = append(paths, m.Path)
+		}
+		return paths
+	}
+	windows := []struct {
+		min, max int64
+		want     []string
+	}{
+		{min: 150 * M, want: []string{"/data/small.log"}},
+		{min: 60 * M, max: 150 * M, want: []string{"/data/old.log"}},
+		{max: 10 * M, want: []string{"/data/b.log", "/data/small.log"}},
+		{min: 300 * M, want: nil},
+	}
+	for _, window := range windows {
+		if got := paths(finder.Result(window.min, window.max)); !reflect.DeepEqual(got, window.want) {
+			t.Errorf("window [%d, %d] = %v; want %v", window.min, window.max, got, window.want)
+		}
+	}
+}
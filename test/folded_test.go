@@ -0,0 +1,46 @@
+package main
+
+import (
+	"reflect"
+	"space-monitor/libs/folded"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestFoldedLine(t *testing.T) {
+	line := folded.Line([]string{"/data", "my dir", "a;b\nc.txt"}, 1024)
+	if want := "/data;my dir;a_b c.txt 1024"; line != want {
+		t.Errorf("line = %q; want %q", line, want)
+	}
+}
+
+func TestFoldedStacks(t *testing.T) {
+	fileMap := map[string]store.FileInfo{
+		"/data":            {IsDir: true, Size: 1600},
+		"/data/a":          {IsDir: true, Size: 1500},
+		"/data/a/x.bin":    {Size: 1000},
+		"/data/a/y;z.bin":  {Size: 500},
+		"/data/top.txt":    {Size: 100},
+		"/database/db.dat": {Size: 7}, // outside the root with the same prefix
+	}
+	want := []string{"/data;a;x.bin 1000", "/data;a;y_z.bin 500", "/data;top.txt 100"}
+	if got := folded.Stacks(fileMap, nil, "/data"); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stacks = %q; want %q", got, want)
+	}
+	if got := folded.Stacks(fileMap, nil, "/data/top.txt"); !reflect.DeepEqual(got, []string{"/data/top.txt 100"}) {
+		t.Errorf("Stacks of a file = %q", got)
+	}
+
+	// growth since the previous map: new and grown files only
+	prevMap := map[string]store.FileInfo{
+		"/data/a/x.bin":   {Size: 400},
+		"/data/a/y;z.bin": {Size: 800},
+		"/data/top.txt":   {Size: 100},
+		"/data/deleted":   {Size: 50},
+	}
+	fileMap["/data/new"] = store.FileInfo{Size: 30}
+	want = []string{"/data;a;x.bin 600", "/data;new 30"}
+	if got := folded.Stacks(fileMap, prevMap, "/data"); !reflect.DeepEqual(got, want) {
+		t.Errorf("growth Stacks = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"encoding/gob"
+	"gopkg.in/yaml.v2"
+	"os"
+	"path/filepath"
+	"reflect"
+	"space-monitor/libs/store"
+	"testing"
+	"time"
+)
+
+var fixtureFileMap = map[string]store.FileInfo{
+	"/data":       {IsDir: true, Size: 30},
+	"/data/a.txt": {Size: 10},
+	"/data/b.txt": {Size: 20, Hash: []byte{7}},
+}
+
+// writeFixtureSnapshot writes a snapshot folder of the format version as older versions did.
+// fileMapExt is the file map extension (empty for no file map)
+func writeFixtureSnapshot(t *testing.T, dataDir string, day int, version int, fileMapExt string) store.Snapshot {
+	t.Helper()
+	start := time.Date(2021, 3, day, 12, 0, 0, 0, time.Local)
+	snap := store.Snapshot{StartTime: start, ID: store.NewSnapshotID(start)}
+	dir := filepath.Join(dataDir, snap.ID)
+	if version == store.CurrentFormatVersion {
+		s, err := store.NewFolderStore(dataDir, "zstd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		info := store.DirInfo{Path: "/data", Size: 30, Files: 2, Dirs: 1}
+		if fileMapExt != "" {
+			info.FileMap = fixtureFileMap
+		}
+		if err := s.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.SaveDirInfo(snap, info); err != nil {
+			t.Fatal(err)
+		}
+		return snap
+	}
+
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		t.Fatal(err)
+	}
+	writeYaml := func(file string, value interface{}) {
+		data, err := yaml.Marshal(value)
+		if err == nil {
+			err = os.WriteFile(filepath.Join(dir, file), data, 0666)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeYaml("snapshot.dat", snap)
+	base := "dirinfo-" + store.PathHash("/data")
+	writeYaml(base+".dat", store.DirInfo{Path: "/data", Size: 30, Files: 2, Dirs: 1})
+	switch fileMapExt {
+	case store.FileMapExtLegacy:
+		file, err := os.Create(filepath.Join(dir, base+fileMapExt))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// noinspection GoUnhandledErrorResult
+		defer file.Close()
+		if err := gob.NewEncoder(file).Encode(fixtureFileMap); err != nil {
+			t.Fatal(err)
+		}
+	case store.FileMapExtGzip:
+		if err := store.SaveFileMap(filepath.Join(dir, base), fixtureFileMap, "gzip"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return snap
+}
+
+func TestReadManifest(t *testing.T) {
+	dataDir := t.TempDir()
+	tests := []struct {
+		version    int
+		fileMapExt string
+		want       int
+	}{
+		{version: 1, fileMapExt: store.FileMapExtLegacy, want: store.FormatVersionLegacy},
+		{version: 2, fileMapExt: store.FileMapExtGzip, want: store.FormatVersionCompressed},
+		{version: 2, fileMapExt: "", want: store.FormatVersionCompressed}, // v1 and v2 without file maps are the same
+		{version: 3, fileMapExt: store.FileMapExtZstd, want: store.FormatVersionManifest},
+	}
+	for i, test := range tests {
+		snap := writeFixtureSnapshot(t, dataDir, i+1, test.version, test.fileMapExt)
+		manifest, err := store.ReadManifest(filepath.Join(dataDir, snap.ID))
+		if err != nil {
+			t.Errorf("v%d%s: %v", test.version, test.fileMapExt, err)
+			continue
+		}
+		if manifest.FormatVersion != test.want {
+			t.Errorf("v%d%s: format version %d; want %d", test.version, test.fileMapExt, manifest.FormatVersion, test.want)
+		}
+		if len(manifest.Dirs) != 1 || manifest.Dirs[0].Path != "/data" {
+			t.Errorf("v%d%s: manifest dirs %+v", test.version, test.fileMapExt, manifest.Dirs)
+		}
+	}
+
+	// a new snapshot folder has the manifest before any directory is saved
+	s, _ := store.NewFolderStore(dataDir, "gzip")
+	start := time.Date(2021, 4, 1, 0, 0, 0, 0, time.Local)
+	if err := s.Save(store.Snapshot{StartTime: start, ID: store.NewSnapshotID(start)}); err != nil {
+		t.Fatal(err)
+	}
+	if manifest, err := store.ReadManifest(filepath.Join(dataDir, store.NewSnapshotID(start))); err != nil || manifest.FormatVersion != store.CurrentFormatVersion {
+		t.Errorf("new snapshot folder: format version %d, %v", manifest.FormatVersion, err)
+	}
+
+	newer := filepath.Join(dataDir, "newer")
+	if err := os.MkdirAll(newer, 0777); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(newer, store.ManifestFileName), []byte("format-version: 99\n"), 0666); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReadManifest(newer); err == nil {
+		t.Errorf("manifest of a newer format: error expected")
+	}
+}
+
+func TestMigrateDataDir(t *testing.T) {
+	dataDir := filepath.Join(t.TempDir(), "data")
+	v1 := writeFixtureSnapshot(t, dataDir, 1, 1, store.FileMapExtLegacy)
+	v2 := writeFixtureSnapshot(t, dataDir, 2, 2, store.FileMapExtGzip)
+	v3 := writeFixtureSnapshot(t, dataDir, 3, 3, store.FileMapExtZstd)
+	if err := os.MkdirAll(filepath.Join(dataDir, "reports"), 0777); err != nil { // not a snapshot
+		t.Fatal(err)
+	}
+
+	outdated, err := store.FindOutdated(dataDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outdated) != 2 || filepath.Base(outdated[0].Dir) != v1.ID || outdated[0].FormatVersion != 1 ||
+		filepath.Base(outdated[1].Dir) != v2.ID || outdated[1].FormatVersion != 2 {
+		t.Fatalf("FindOutdated = %+v; want v1 and v2 snapshots", outdated)
+	}
+
+	backupDir := dataDir + "-backup"
+	if err := store.MigrateDataDir(dataDir, backupDir, outdated, "zstd"); err != nil {
+		t.Fatal(err)
+	}
+	gobFile := "dirinfo-" + store.PathHash("/data") + store.FileMapExtLegacy
+	if _, err := os.Stat(filepath.Join(backupDir, v1.ID, gobFile)); err != nil {
+		t.Errorf("backup has no original file map: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dataDir, v1.ID, gobFile)); !os.IsNotExist(err) {
+		t.Errorf("legacy file map is not removed after migration: %v", err)
+	}
+	if outdated, err := store.FindOutdated(dataDir); err != nil || len(outdated) != 0 {
+		t.Errorf("FindOutdated after migration = %+v, %v", outdated, err)
+	}
+	if outdated, err := store.FindOutdated(backupDir); err != nil || len(outdated) != 2 {
+		t.Errorf("backup is migrated too: %+v, %v", outdated, err)
+	}
+
+	s, err := store.NewFolderStore(dataDir, "zstd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, snap := range []store.Snapshot{v1, v2, v3} {
+		info, err := s.LoadDirInfo(snap, "/data", true)
+		if err != nil {
+			t.Errorf("%s: %v", snap.ID, err)
+			continue
+		}
+		if info.Size != 30 || !reflect.DeepEqual(info.FileMap, fixtureFileMap) {
+			t.Errorf("%s: size %d, file map %v", snap.ID, info.Size, info.FileMap)
+		}
+	}
+}
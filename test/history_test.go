@@ -0,0 +1,97 @@
+package main
+
+import (
+	"space-monitor/libs/history"
+	"space-monitor/libs/store"
+	"testing"
+	"time"
+)
+
+func TestSubtreeStats(t *testing.T) {
+	fileMap := map[string]store.FileInfo{
+		"/data":            {IsDir: true, Size: 175},
+		"/data/sub":        {IsDir: true, Size: 150},
+		"/data/sub/a":      {Size: 100},
+		"/data/sub/deep":   {IsDir: true, Size: 50},
+		"/data/sub/deep/b": {Size: 50},
+		"/data/subway":     {Size: 25}, // same prefix, but not inside
+	}
+	tests := []struct {
+		path    string
+		size    int64
+		files   int
+		present bool
+	}{
+		{path: "/data", size: 175, files: 3, present: true},
+		{path: "/data/sub", size: 150, files: 2, present: true},
+		{path: "/data/sub/a", size: 100, files: 1, present: true},
+		{path: "/data/missing"},
+	}
+	for _, test := range tests {
+		size, files, present := history.SubtreeStats(fileMap, test.path)
+		if size != test.size || files != test.files || present != test.present {
+			t.Errorf("SubtreeStats(%s) = %d, %d, %v; want %d, %d, %v", test.path, size, files, present, test.size, test.files, test.present)
+		}
+	}
+}
+
+func TestPathHistory(t *testing.T) {
+	s := store.NewMemoryStore()
+	base := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	fileMaps := []map[string]store.FileInfo{
+		{"/data": {IsDir: true, Size: 100}, "/data/sub": {IsDir: true, Size: 100}, "/data/sub/a": {Size: 100}},
+		{"/data": {IsDir: true, Size: 10}, "/data/b": {Size: 10}}, // the subtree was deleted
+		nil, // detailed mode was off
+		{"/data": {IsDir: true, Size: 300}, "/data/sub": {IsDir: true, Size: 300}, "/data/sub/a": {Size: 200}, "/data/sub/c": {Size: 100}},
+	}
+	var snapshots []store.Snapshot
+	for i, fileMap := range fileMaps {
+		start := base.Add(time.Duration(i) * 24 * time.Hour)
+		snapshot := store.Snapshot{ID: store.NewSnapshotID(start), StartTime: start}
+		snapshots = append(snapshots, snapshot)
+		_ = s.Save(snapshot)
+		info := store.DirInfo{Path: "/data", Size: int64(1000 * (i + 1)), Files: i + 1, FileMap: fileMap}
+		if err := s.SaveDirInfo(snapshot, info); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a snapshot where the directory wasn't scanned
+	other := store.Snapshot{ID: store.NewSnapshotID(base.Add(-time.Hour)), StartTime: base.Add(-time.Hour)}
+	_ = s.Save(other)
+	snapshots = append([]store.Snapshot{other}, snapshots...)
+
+	var logged int
+	log := func(v ...any) { logged++ }
+	points, err := history.Path(s, snapshots, "/data", "/data/sub", time.Time{}, log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []history.Point{
+		{Snapshot: snapshots[1], Present: true, Size: 100, Files: 1},
+		{Snapshot: snapshots[2], Present: false},
+		{Snapshot: snapshots[4], Present: true, Size: 300, Files: 2},
+	}
+	if len(points) != len(want) {
+		t.Fatalf("got %d points; want %d: %+v", len(points), len(want), points)
+	}
+	for i := range want {
+		if points[i].Snapshot.ID != want[i].Snapshot.ID || points[i].Present != want[i].Present || points[i].Size != want[i].Size || points[i].Files != want[i].Files {
+			t.Errorf("point %d = %+v; want %+v", i, points[i], want[i])
+		}
+	}
+	if logged != 1 {
+		t.Errorf("the snapshot without the file map is logged %d times; want 1", logged)
+	}
+
+	// the configured directory itself is read from the aggregates of every snapshot
+	points, _ = history.Path(s, snapshots, "/data", "/data", time.Time{}, log)
+	if len(points) != 4 || points[2].Size != 3000 || points[2].Files != 3 || !points[2].Present {
+		t.Errorf("directory history: %+v", points)
+	}
+
+	// -since
+	points, _ = history.Path(s, snapshots, "/data", "/data", snapshots[3].StartTime, log)
+	if len(points) != 2 || points[0].Snapshot.ID != snapshots[3].ID || points[1].Snapshot.ID != snapshots[4].ID {
+		t.Errorf("history since %s: %+v", snapshots[3].ID, points)
+	}
+}
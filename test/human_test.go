@@ -0,0 +1,79 @@
+package main
+
+import (
+	"space-monitor/libs/human"
+	"testing"
+	"time"
+)
+
+func TestParseSize(t *testing.T) {
+	tests := map[string]int64{
+		"512":    512,
+		"100K":   100 * 1024,
+		"1.5G":   3 * 512 * 1024 * 1024,
+		"10GiB":  10 * 1024 * 1024 * 1024,
+		"2mb":    2 * 1024 * 1024,
+		" 1T ":   1024 * 1024 * 1024 * 1024,
+		"0":      0,
+		"1024B":  1024,
+		"0.5K":   512,
+		"3E":     3 * 1024 * 1024 * 1024 * 1024 * 1024 * 1024,
+		"0.001K": 1,
+	}
+	for str, want := range tests {
+		if got, err := human.ParseSize(str); err != nil || got != want {
+			t.Errorf("ParseSize(%q) = %d, %v; want %d", str, got, err, want)
+		}
+	}
+	for _, str := range []string{"", "B", "abc", "10X", "G"} {
+		if _, err := human.ParseSize(str); err == nil {
+			t.Errorf("ParseSize(%q): error expected", str)
+		}
+	}
+}
+
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+	tests := map[string]time.Duration{
+		"7d":    7 * day,
+		"2w":    14 * day,
+		"1y":    365 * day,
+		"1.5d":  36 * time.Hour,
+		" 3d ":  3 * day,
+		"90m":   90 * time.Minute,
+		"12h":   12 * time.Hour,
+		"1h30m": 90 * time.Minute,
+	}
+	for str, want := range tests {
+		if got, err := human.ParseDuration(str); err != nil || got != want {
+			t.Errorf("ParseDuration(%q) = %v, %v; want %v", str, got, err, want)
+		}
+	}
+	for _, str := range []string{"", "d", "xd", "7 days", "soon"} {
+		if _, err := human.ParseDuration(str); err == nil {
+			t.Errorf("ParseDuration(%q): error expected", str)
+		}
+	}
+}
+
+func TestSize(t *testing.T) {
+	tests := map[int64]string{
+		0:                      "0B",
+		1023:                   "1023B",
+		1024:                   "1.0K",
+		-1536:                  "-1.5K",
+		5 * 1024 * 1024:        "5.0M",
+		3 * 1024 * 1024 * 1024: "3.0G",
+	}
+	for bytes, want := range tests {
+		if got := human.Size(bytes); got != want {
+			t.Errorf("Size(%d) = %q; want %q", bytes, got, want)
+		}
+	}
+	if got := human.SizeSign(1024); got != "+1.0K" {
+		t.Errorf("SizeSign(1024) = %q", got)
+	}
+	if got := human.SizeSign(-1024); got != "-1.0K" {
+		t.Errorf("SizeSign(-1024) = %q", got)
+	}
+}
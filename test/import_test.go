@@ -0,0 +1,40 @@
+package main
+
+import (
+	"reflect"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestNewImportedDirInfo(t *testing.T) {
+	fileMap := map[string]store.FileInfo{ // sizes of files only, like du and ncdu dumps have
+		"/data":                 {IsDir: true, Size: 4096},
+		"/data/a.txt":           {Size: 10},
+		"/data/docs/b.txt":      {Size: 20}, // parent directory is not listed
+		"/data/docs/old/c.txt":  {Size: 5},
+		"/data/empty":           {IsDir: true},
+		"/elsewhere/other.txt":  {Size: 1000}, // outside of the root
+		"/data-backup/skip.txt": {Size: 1000}, // shares the prefix only
+	}
+	info := store.NewImportedDirInfo("/data", fileMap)
+
+	want := map[string]store.FileInfo{
+		"/data":                {IsDir: true, Size: 35},
+		"/data/a.txt":          {Size: 10},
+		"/data/docs":           {IsDir: true, Size: 25},
+		"/data/docs/b.txt":     {Size: 20},
+		"/data/docs/old":       {IsDir: true, Size: 5},
+		"/data/docs/old/c.txt": {Size: 5},
+		"/data/empty":          {IsDir: true},
+	}
+	if !reflect.DeepEqual(info.FileMap, want) {
+		t.Errorf("file map = %v; want %v", info.FileMap, want)
+	}
+	if info.Path != "/data" || info.Size != 35 || info.Files != 3 || info.Dirs != 4 {
+		t.Errorf("path %s, size %d, files %d, dirs %d; want /data, 35, 3, 4", info.Path, info.Size, info.Files, info.Dirs)
+	}
+
+	if info := store.NewImportedDirInfo("/data", nil); info.Size != 0 || info.Dirs != 1 || !info.FileMap["/data"].IsDir {
+		t.Errorf("empty dump: %+v", info)
+	}
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"bufio"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"io"
+	"math/big"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"space-monitor/libs/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMessageBytes(t *testing.T) {
+	message := mail.Message{
+		From:    "Space Monitor <monitor@example.com>",
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "[space-monitor] HOST: WARNING, 1.0G free ✓",
+		Text:    "plain report\nwith a long line " + strings.Repeat("=", 100),
+		HTML:    "<pre>report ✓</pre>",
+		Date:    time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	data, err := message.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject")); err != nil || subject != message.Subject {
+		t.Errorf("Subject = %q, %v", subject, err)
+	}
+	if to := parsed.Header.Get("To"); to != "a@example.com, b@example.com" {
+		t.Errorf("To = %q", to)
+	}
+	if date, err := parsed.Header.Date(); err != nil || !date.Equal(message.Date) {
+		t.Errorf("Date = %v, %v", date, err)
+	}
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Content-Type = %s, %v", mediaType, err)
+	}
+
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	for _, want := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", message.Text},
+		{"text/html; charset=utf-8", message.HTML},
+	} {
+		part, err := reader.NextPart() // decodes quoted-printable
+		if err != nil {
+			t.Fatalf("%s part: %v", want.contentType, err)
+		}
+		body, _ := io.ReadAll(part)
+		text := strings.ReplaceAll(string(body), "\r\n", "\n") // line breaks are CRLF on the wire
+		if part.Header.Get("Content-Type") != want.contentType || text != want.body {
+			t.Errorf("part %s = %q; want %s %q", part.Header.Get("Content-Type"), body, want.contentType, want.body)
+		}
+	}
+	if _, err := reader.NextPart(); err != io.EOF {
+		t.Errorf("unexpected third part: %v", err)
+	}
+
+	message.HTML = "" // plain text only
+	data, _ = message.Bytes()
+	if strings.Contains(string(data), "text/html") {
+		t.Errorf("message without HTML has the HTML part")
+	}
+}
+
+func TestServerValidate(t *testing.T) {
+	tests := []struct {
+		server mail.Server
+		valid  bool
+	}{
+		{server: mail.Server{Host: "smtp.example.com", Security: "starttls", Username: "user"}, valid: true},
+		{server: mail.Server{Host: "smtp.example.com", Security: "none"}, valid: true},
+		{server: mail.Server{Host: "localhost", Security: "none", Username: "user"}, valid: true},
+		{server: mail.Server{Host: "smtp.example.com", Security: "none", Username: "user"}, valid: false},
+	}
+	for _, test := range tests {
+		if err := test.server.Validate(); (err == nil) != test.valid {
+			t.Errorf("%s %s with username %q: %v", test.server.Host, test.server.Security, test.server.Username, err)
+		}
+	}
+}
+
+// fakeSMTP is a minimal SMTP server accepting one message per connection
+type fakeSMTP struct {
+	listener net.Listener
+	tls      *tls.Config // STARTTLS is offered if set
+	commands chan []string
+	data     chan string
+}
+
+func newFakeSMTP(t *testing.T, tlsConfig *tls.Config) *fakeSMTP {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &fakeSMTP{listener: listener, tls: tlsConfig, commands: make(chan []string, 1), data: make(chan string, 1)}
+	go server.serve()
+	t.Cleanup(func() { _ = listener.Close() })
+	return server
+}
+
+func (s *fakeSMTP) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTP) serve() {
+	conn, err := s.listener.Accept()
+	if err != nil {
+		return
+	}
+	// noinspection GoUnhandledErrorResult
+	defer conn.Close()
+	var commands []string
+	defer func() { s.commands <- commands }()
+	reader, writer := bufio.NewReader(conn), bufio.NewWriter(conn)
+	reply := func(lines ...string) {
+		_, _ = writer.WriteString(strings.Join(lines, "\r\n") + "\r\n")
+		_ = writer.Flush()
+	}
+	reply("220 fake ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		line = strings.TrimSpace(line)
+		verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		commands = append(commands, verb)
+		switch verb {
+		case "EHLO":
+			_, secure := conn.(*tls.Conn)
+			if s.tls != nil && !secure {
+				reply("250-fake", "250-STARTTLS", "250 AUTH PLAIN")
+			} else {
+				reply("250-fake", "250 AUTH PLAIN")
+			}
+		case "STARTTLS":
+			reply("220 ready")
+			tlsConn := tls.Server(conn, s.tls)
+			if err := tlsConn.Handshake(); err != nil {
+				return
+			}
+			conn = tlsConn
+			reader, writer = bufio.NewReader(conn), bufio.NewWriter(conn)
+		case "AUTH":
+			reply("235 authenticated")
+		case "MAIL", "RCPT", "RSET", "NOOP":
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			s.data <- data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unknown command")
+		}
+	}
+}
+
+// selfSignedTLS returns server and client TLS settings with a certificate of 127.0.0.1
+func selfSignedTLS(t *testing.T) (*tls.Config, *tls.Config) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	server := &tls.Config{Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}}}
+	client := &tls.Config{ServerName: "127.0.0.1", RootCAs: pool}
+	return server, client
+}
+
+func TestServerSend(t *testing.T) {
+	serverTLS, clientTLS := selfSignedTLS(t)
+	message := mail.Message{From: "Monitor <monitor@example.com>", To: []string{"admin@example.com"}, Subject: "report", Text: "hello"}
+	tests := []struct {
+		name     string
+		server   *fakeSMTP
+		settings mail.Server
+		want     string // commands of the session
+	}{
+		{name: "starttls", server: newFakeSMTP(t, serverTLS),
+			settings: mail.Server{Host: "127.0.0.1", Security: "starttls", Username: "user", Password: "secret", TLS: clientTLS},
+			want:     "EHLO STARTTLS EHLO AUTH MAIL RCPT DATA QUIT"},
+		{name: "none", server: newFakeSMTP(t, nil),
+			settings: mail.Server{Host: "127.0.0.1", Security: "none", Username: "user", Password: "secret"},
+			want:     "EHLO AUTH MAIL RCPT DATA QUIT"},
+		{name: "none without authentication", server: newFakeSMTP(t, nil),
+			settings: mail.Server{Host: "127.0.0.1", Security: "none"},
+			want:     "EHLO MAIL RCPT DATA QUIT"},
+	}
+	for _, test := range tests {
+		test.settings.Port = test.server.port()
+		test.settings.Timeout = 5 * time.Second
+		if err := test.settings.Send(message); err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if commands := strings.Join(<-test.server.commands, " "); commands != test.want {
+			t.Errorf("%s: commands %q; want %q", test.name, commands, test.want)
+		}
+		if data := <-test.server.data; !strings.Contains(data, "Subject: report") || !strings.Contains(data, "hello") {
+			t.Errorf("%s: unexpected message %q", test.name, data)
+		}
+	}
+
+	// the server without STARTTLS is rejected instead of sending the password in plain text
+	server := newFakeSMTP(t, nil)
+	settings := mail.Server{Host: "127.0.0.1", Port: server.port(), Security: "starttls", Username: "user", Timeout: 5 * time.Second}
+	if err := settings.Send(message); err == nil {
+		t.Errorf("starttls to a server without STARTTLS: error expected")
+	}
+	if commands := <-server.commands; strings.Contains(strings.Join(commands, " "), "AUTH") {
+		t.Errorf("credentials are sent without TLS: %v", commands)
+	}
+}
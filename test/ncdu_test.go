@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"space-monitor/libs/ncdu"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNcduWrite(t *testing.T) {
+	root := &ncdu.Entry{Name: "/data", IsDir: true, Children: []*ncdu.Entry{
+		{Name: "a.txt", Size: 10},
+		{Name: "sub", IsDir: true, Children: []*ncdu.Entry{{Name: "b \"quoted\"", Size: 20}}},
+	}}
+	var buffer bytes.Buffer
+	if err := ncdu.Write(&buffer, root, "test", "1", time.Unix(1700000000, 0)); err != nil {
+		t.Fatal(err)
+	}
+	var export []any
+	if err := json.Unmarshal(buffer.Bytes(), &export); err != nil {
+		t.Fatalf("invalid json: %v\n%s", err, buffer.String())
+	}
+	if len(export) != 4 || export[0] != 1.0 || export[1] != 2.0 {
+		t.Fatalf("unexpected header: %v", export)
+	}
+	if meta := export[2].(map[string]any); meta["progname"] != "test" || meta["timestamp"] != 1700000000.0 {
+		t.Errorf("unexpected metadata: %v", meta)
+	}
+	tree := export[3].([]any)
+	if len(tree) != 3 || tree[0].(map[string]any)["name"] != "/data" {
+		t.Fatalf("unexpected root: %v", tree)
+	}
+	if file := tree[1].(map[string]any); file["name"] != "a.txt" || file["asize"] != 10.0 {
+		t.Errorf("unexpected file: %v", file)
+	}
+	sub := tree[2].([]any)
+	if sub[0].(map[string]any)["name"] != "sub" || sub[1].(map[string]any)["name"] != "b \"quoted\"" {
+		t.Errorf("unexpected subdirectory: %v", sub)
+	}
+}
+
+func TestNcduRead(t *testing.T) {
+	export := `[1,2,{"progname":"ncdu","progver":"1.18","timestamp":1600000000},
+[{"name":"/data","asize":4096,"dsize":4096,"dev":2049,"ino":2},
+{"name":"a.txt","asize":10,"dsize":4096,"ino":3},
+[{"name":"sub","asize":4096},{"name":"b","asize":20}],
+{"name":"skipped","excluded":"pattern"}]]`
+	root, timestamp, err := ncdu.Read(strings.NewReader(export))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timestamp.Unix() != 1600000000 {
+		t.Errorf("timestamp = %v", timestamp)
+	}
+	if root.Name != "/data" || !root.IsDir || len(root.Children) != 3 {
+		t.Fatalf("unexpected root: %+v", root)
+	}
+	if a := root.Children[0]; a.Name != "a.txt" || a.IsDir || a.Size != 10 {
+		t.Errorf("unexpected file: %+v", a)
+	}
+	if sub := root.Children[1]; !sub.IsDir || len(sub.Children) != 1 || sub.Children[0].Size != 20 {
+		t.Errorf("unexpected directory: %+v", sub)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"space-monitor/libs/alerting"
+	"space-monitor/libs/owners"
+	"space-monitor/libs/store"
+	"testing"
+)
+
+func TestOwnersValidate(t *testing.T) {
+	valid := []owners.Config{
+		{},
+		{Quotas: map[string]string{"alice": "10G"}, Warning: "80%"},
+		{Groups: true, Quotas: map[string]string{"@staff": "1T"}},
+	}
+	for _, c := range valid {
+		if err := c.Validate(); err != nil {
+			t.Errorf("%+v: %v", c, err)
+		}
+	}
+	invalid := []owners.Config{
+		{Quotas: map[string]string{"alice": "lots"}},
+		{Quotas: map[string]string{"alice": "0"}},
+		{Quotas: map[string]string{"alice": "-1G"}},
+		{Quotas: map[string]string{"@staff": "1T"}}, // groups are not collected
+		{Warning: "80"},
+		{Warning: "120%"},
+	}
+	for _, c := range invalid {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v: error expected", c)
+		}
+	}
+}
+
+func TestOwnersWarningPercent(t *testing.T) {
+	if percent := (owners.Config{}).WarningPercent(); percent != 90 {
+		t.Errorf("default warning = %v; want 90", percent)
+	}
+	if percent := (owners.Config{Warning: "75%"}).WarningPercent(); percent != 75 {
+		t.Errorf("warning = %v; want 75", percent)
+	}
+}
+
+func TestQuotaAlerts(t *testing.T) {
+	const G = 1024 * 1024 * 1024
+	c := owners.Config{Groups: true, Warning: "80%", Quotas: map[string]string{
+		"alice": "10G", "bob": "10G", "carol": "10G", "@staff": "100G",
+	}}
+	totals := map[string]store.UsageStat{
+		"alice":  {Size: 12 * G, Files: 10},  // over the quota
+		"bob":    {Size: 8 * G, Files: 10},   // at the warning percent
+		"carol":  {Size: 7 * G, Files: 10},   // below the warning
+		"dave":   {Size: 500 * G, Files: 10}, // no quota
+		"@staff": {Size: 27 * G, Files: 30},
+	}
+	alerts := c.QuotaAlerts(totals)
+	want := []alerting.Alert{
+		{Level: alerting.Critical, Rule: "quota of alice", Message: "alice uses 12.0G of 10G quota (120%)"},
+		{Level: alerting.Warning, Rule: "quota of bob", Message: "bob uses 8.0G of 10G quota (80%)"},
+	}
+	if len(alerts) != len(want) {
+		t.Fatalf("got %v; want %v", alerts, want)
+	}
+	for i := range want {
+		if alerts[i] != want[i] {
+			t.Errorf("alert %d = %v; want %v", i, alerts[i], want[i])
+		}
+	}
+
+	if percent, level, ok := c.QuotaUsage("@staff", 27*G); !ok || percent != 27 || level != alerting.OK {
+		t.Errorf("QuotaUsage(@staff) = %v, %v, %v", percent, level, ok)
+	}
+	if _, _, ok := c.QuotaUsage("dave", G); ok {
+		t.Errorf("QuotaUsage of a user without quota: false expected")
+	}
+}
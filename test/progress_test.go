@@ -0,0 +1,89 @@
+package main
+
+import (
+	"space-monitor/libs/progress"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressRate(t *testing.T) {
+	if rate := progress.Rate(500, 2*time.Second); rate != 250 {
+		t.Errorf("Rate = %v; want 250", rate)
+	}
+	if rate := progress.Rate(500, 0); rate != 0 {
+		t.Errorf("Rate at start = %v; want 0", rate)
+	}
+}
+
+func TestProgressETA(t *testing.T) {
+	tests := []struct {
+		seen, expected int
+		elapsed        time.Duration
+		percent        int
+		eta            time.Duration
+		ok             bool
+	}{
+		{seen: 250, expected: 1000, elapsed: 10 * time.Second, percent: 25, eta: 30 * time.Second, ok: true},
+		{seen: 999, expected: 1000, elapsed: 999 * time.Millisecond, percent: 99, eta: time.Millisecond, ok: true},
+		{seen: 100, expected: 0, elapsed: time.Second},     // unknown
+		{seen: 0, expected: 1000, elapsed: time.Second},    // nothing walked yet
+		{seen: 100, expected: 1000},                        // just started
+		{seen: 1200, expected: 1000, elapsed: time.Second}, // the directory grew
+		{seen: 1000, expected: 1000, elapsed: time.Second},
+	}
+	for _, test := range tests {
+		percent, eta, ok := progress.ETA(test.seen, test.expected, test.elapsed)
+		if percent != test.percent || eta.Round(time.Millisecond) != test.eta || ok != test.ok {
+			t.Errorf("ETA(%d, %d, %v) = %d%%, %v, %v; want %d%%, %v, %v", test.seen, test.expected, test.elapsed,
+				percent, eta, ok, test.percent, test.eta, test.ok)
+		}
+	}
+}
+
+func TestProgressScan(t *testing.T) {
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	scan := progress.Scan{
+		Now:       func() time.Time { return clock },
+		ShortPath: func(path string) string { return strings.Replace(path, "/home/user", "~", 1) },
+	}
+	if line := scan.String(); line != "" {
+		t.Errorf("line before the scan = %q", line)
+	}
+
+	scan.Begin(2)
+	scan.StartDir("/home/user", 400)
+	scan.Add("/home/user", true, 0)
+	for i := 0; i < 99; i++ {
+		scan.Add("/home/user/file", false, 1024)
+	}
+	if !scan.Walking() {
+		t.Errorf("not walking")
+	}
+	clock = clock.Add(500 * time.Millisecond) // no rate in the first second
+	if want := "scanning ~ (1/2): 99 files, 1 dirs, 99.0K, 25% ETA 2s ~/file"; scan.String() != want {
+		t.Errorf("line = %q; want %q", scan.String(), want)
+	}
+	clock = clock.Add(1500 * time.Millisecond)
+	if want := "scanning ~ (1/2): 99 files, 1 dirs, 99.0K, 50/s, 25% ETA 6s ~/file"; scan.String() != want {
+		t.Errorf("line = %q; want %q", scan.String(), want)
+	}
+
+	scan.EndDir()
+	clock = clock.Add(time.Second)
+	if want := "scanning (1/2 dirs done), 3s"; scan.String() != want {
+		t.Errorf("line between dirs = %q; want %q", scan.String(), want)
+	}
+
+	scan.StartDir("/var", 0) // unknown number of entries: no ETA
+	scan.Add("/var/log", true, 0)
+	clock = clock.Add(2 * time.Second)
+	if want := "scanning /var (2/2): 0 files, 1 dirs, 0B, 0/s /var/log"; scan.String() != want {
+		t.Errorf("line without expected entries = %q; want %q", scan.String(), want)
+	}
+
+	scan.Finish()
+	if scan.Walking() || scan.String() != "" {
+		t.Errorf("finished scan: walking %v, line %q", scan.Walking(), scan.String())
+	}
+}
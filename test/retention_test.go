@@ -0,0 +1,83 @@
+package main
+
+import (
+	"sort"
+	"space-monitor/libs/retention"
+	"space-monitor/libs/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRetentionEvaluate(t *testing.T) {
+	now := time.Date(2021, 6, 15, 12, 0, 0, 0, time.UTC)
+	ages := map[string]time.Duration{ // snapshot name -> age
+		"1h": time.Hour, "2h": 2 * time.Hour, "26h": 26 * time.Hour, "50h": 50 * time.Hour,
+		"10d": 10 * 24 * time.Hour, "40d": 40 * 24 * time.Hour, "100d": 100 * 24 * time.Hour, "400d": 400 * 24 * time.Hour,
+	}
+	var snapshots []store.Snapshot
+	for name, age := range ages {
+		snap := store.Snapshot{StartTime: now.Add(-age), ID: name}
+		if name == "100d" {
+			snap.Tags = []string{"baseline"}
+		}
+		snapshots = append(snapshots, snap)
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].StartTime.Before(snapshots[j].StartTime) })
+	size := func(store.Snapshot) (int64, error) { return 100, nil }
+
+	tests := []struct {
+		name   string
+		policy retention.Policy
+		kept   string // names of the kept snapshots, newer first
+	}{
+		{"keep-last", retention.Policy{KeepLast: 2}, "1h 2h 100d"},
+		{"keep-hourly", retention.Policy{KeepHourly: 3}, "1h 2h 26h 100d"},
+		{"keep-daily", retention.Policy{KeepDaily: 3}, "1h 26h 50h 100d"},
+		{"keep-weekly", retention.Policy{KeepWeekly: 2}, "1h 50h 100d"}, // now is Tuesday, 50h ago is Sunday of the previous week
+		{"keep-monthly", retention.Policy{KeepMonthly: 3}, "1h 40d 100d 400d"},
+		{"combined", retention.Policy{KeepLast: 1, KeepDaily: 2, KeepMonthly: 2}, "1h 26h 40d 100d"},
+		// max-age removes old snapshots even if keep rules match them, pinned ones stay
+		{"max-age", retention.Policy{KeepMonthly: 3, MaxAge: "90d"}, "1h 40d 100d"},
+		{"max-age all", retention.Policy{KeepLast: 10, MaxAge: "1d"}, "1h 2h 100d"},
+		// 8 snapshots of 100 bytes: the oldest unpinned ones are removed until the total fits
+		{"max-total-bytes", retention.Policy{KeepLast: 10, MaxTotalBytes: "350"}, "1h 2h 100d"},
+	}
+	for _, test := range tests {
+		decisions, err := retention.Evaluate(test.policy, snapshots, now, size)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if len(decisions) != len(snapshots) || decisions[0].Snapshot.ID != "1h" {
+			t.Errorf("%s: decisions are not newer first", test.name)
+		}
+		var kept []string
+		for _, d := range decisions {
+			if d.Keep {
+				kept = append(kept, d.Snapshot.ID)
+			}
+			if len(d.Reasons) == 0 {
+				t.Errorf("%s: no reason for %s", test.name, d.Snapshot.ID)
+			}
+		}
+		if got := strings.Join(kept, " "); got != test.kept {
+			t.Errorf("%s: kept %q; want %q", test.name, got, test.kept)
+		}
+	}
+
+	decisions, _ := retention.Evaluate(retention.Policy{KeepLast: 10, MaxAge: "90d"}, snapshots, now, size)
+	for _, d := range decisions {
+		if d.Snapshot.ID == "400d" && (d.Keep || !strings.Contains(d.Reasons[0], "max-age")) {
+			t.Errorf("400d: keep %v, reasons %v; want removal by max-age", d.Keep, d.Reasons)
+		}
+	}
+	for _, policy := range []retention.Policy{{KeepLast: 1, MaxAge: "soon"}, {KeepLast: 1, MaxTotalBytes: "lots"}} {
+		if _, err := retention.Evaluate(policy, snapshots, now, size); err == nil {
+			t.Errorf("invalid policy %+v: error expected", policy)
+		}
+	}
+	if (retention.Policy{MaxAge: "1d"}).HasKeepRules() || !(retention.Policy{KeepWeekly: 1}).HasKeepRules() {
+		t.Errorf("HasKeepRules is wrong")
+	}
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"space-monitor/libs/sparkline"
+	"testing"
+)
+
+func TestSparkline(t *testing.T) {
+	cases := []struct {
+		values []int64
+		want   string
+	}{
+		{nil, ""},
+		{[]int64{5, 5, 5}, "▁▁▁"},
+		{[]int64{0, 7}, "▁█"},
+		{[]int64{0, 1, 2, 3, 4, 5, 6, 7}, "▁▂▃▄▅▆▇█"},
+		{[]int64{-10, 10}, "▁█"},
+	}
+	for _, c := range cases {
+		if got := sparkline.Render(c.values); got != c.want {
+			t.Errorf("Render(%v) = %q, want %q", c.values, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"path/filepath"
+	"space-monitor/libs/store"
+	"testing"
+	"time"
+)
+
+// testStoreContract checks the behaviour every SnapshotStore implementation must have
+func testStoreContract(t *testing.T, s store.SnapshotStore) {
+	t.Helper()
+	base := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	var snapshots []store.Snapshot
+	for i := 0; i < 3; i++ {
+		start := base.Add(time.Duration(i) * 24 * time.Hour)
+		snapshots = append(snapshots, store.Snapshot{StartTime: start, FreeSpace: int64(100 - i), ID: store.NewSnapshotID(start)})
+	}
+	snapshots[0].AddTag("baseline")
+
+	// saved in reverse order: the store has to sort them
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if err := s.Save(snapshots[i]); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		info := store.DirInfo{Path: "/data", Size: int64(10 * (i + 1)), Files: i + 1, Dirs: 1, StartTime: snapshots[i].StartTime}
+		if i == 1 {
+			info.FileMap = map[string]store.FileInfo{
+				"/data":       {IsDir: true, Size: 20},
+				"/data/a.txt": {Size: 20, Hash: []byte{1, 2, 3}},
+			}
+		}
+		if err := s.SaveDirInfo(snapshots[i], info); err != nil {
+			t.Fatalf("SaveDirInfo: %v", err)
+		}
+	}
+	if err := s.Save(store.Snapshot{}); err == nil {
+		t.Errorf("Save of a snapshot without id: error expected")
+	}
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("List returned %d snapshots; want 3", len(list))
+	}
+	for i, snap := range list {
+		if snap.ID != snapshots[i].ID || snap.FreeSpace != snapshots[i].FreeSpace || !snap.StartTime.Equal(snapshots[i].StartTime) {
+			t.Errorf("List()[%d] = %s %d; want %s %d", i, snap.ID, snap.FreeSpace, snapshots[i].ID, snapshots[i].FreeSpace)
+		}
+	}
+	if !list[0].HasTag("baseline") || list[1].HasTag("baseline") {
+		t.Errorf("tags are not stored: %v %v", list[0].Tags, list[1].Tags)
+	}
+
+	loaded, err := s.Load(snapshots[2].ID)
+	if err != nil || loaded.ID != snapshots[2].ID {
+		t.Errorf("Load(%s) = %s, %v", snapshots[2].ID, loaded.ID, err)
+	}
+	if _, err := s.Load("1999-01-01 00:00:00"); err == nil {
+		t.Errorf("Load of a missing snapshot: error expected")
+	}
+
+	ranged, err := s.ListRange(base.Add(time.Hour), base.Add(48*time.Hour))
+	if err != nil {
+		t.Fatalf("ListRange: %v", err)
+	}
+	if len(ranged) != 2 || ranged[0].ID != snapshots[1].ID || ranged[1].ID != snapshots[2].ID {
+		t.Errorf("ListRange returned %d snapshots; want the last two", len(ranged))
+	}
+
+	info, err := s.LoadDirInfo(snapshots[1], "/data", true)
+	if err != nil {
+		t.Fatalf("LoadDirInfo: %v", err)
+	}
+	if info.Size != 20 || info.Files != 2 || info.SnapshotID != snapshots[1].ID {
+		t.Errorf("LoadDirInfo = size %d, files %d, snapshot %q", info.Size, info.Files, info.SnapshotID)
+	}
+	if file := info.FileMap["/data/a.txt"]; file.Size != 20 || len(file.Hash) != 3 || len(info.FileMap) != 2 {
+		t.Errorf("LoadDirInfo file map = %v", info.FileMap)
+	}
+	if info, err := s.LoadDirInfo(snapshots[1], "/data", false); err != nil || info.FileMap != nil {
+		t.Errorf("LoadDirInfo without details: file map %v, err %v", info.FileMap, err)
+	}
+	if _, err := s.LoadDirInfo(snapshots[0], "/data", true); err == nil {
+		t.Errorf("LoadDirInfo of a dir without file map in detailed mode: error expected")
+	}
+	if _, err := s.LoadDirInfo(snapshots[0], "/missing", false); err == nil {
+		t.Errorf("LoadDirInfo of a missing dir: error expected")
+	}
+
+	history, err := s.History("/data")
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("History returned %d entries; want 3", len(history))
+	}
+	for i, info := range history {
+		if info.SnapshotID != snapshots[i].ID || info.Size != int64(10*(i+1)) || info.FileMap != nil {
+			t.Errorf("History()[%d] = %s size %d", i, info.SnapshotID, info.Size)
+		}
+	}
+
+	if err := s.Delete(snapshots[1]); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if list, _ := s.List(); len(list) != 2 || list[1].ID != snapshots[2].ID {
+		t.Errorf("List after Delete returned %d snapshots", len(list))
+	}
+	if history, _ := s.History("/data"); len(history) != 2 {
+		t.Errorf("History after Delete returned %d entries; want 2", len(history))
+	}
+	if _, err := s.LoadDirInfo(snapshots[1], "/data", false); err == nil {
+		t.Errorf("LoadDirInfo of a deleted snapshot: error expected")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStoreContract(t, store.NewMemoryStore())
+}
+
+func TestFolderStore(t *testing.T) {
+	for _, compression := range []string{"gzip", "zstd", "none"} {
+		s, err := store.NewFolderStore(t.TempDir(), compression)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testStoreContract(t, s)
+	}
+}
+
+func TestBoltStore(t *testing.T) {
+	dir := t.TempDir()
+	s, err := store.OpenBoltStore(filepath.Join(dir, "history.db"), dir, "zstd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// noinspection GoUnhandledErrorResult
+	defer s.Close()
+	testStoreContract(t, s)
+}
+
+func TestBoltStoreReopen(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "history.db")
+	s, err := store.OpenBoltStore(file, dir, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local)
+	snap := store.Snapshot{StartTime: start, ID: store.NewSnapshotID(start), Tags: []string{"baseline"}}
+	if err := s.Save(snap); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveDirInfo(snap, store.DirInfo{Path: "/data", Size: 5, FileMap: map[string]store.FileInfo{"/data": {IsDir: true}}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = store.OpenBoltStore(file, dir, "zstd") // file maps written with another compression stay readable
+	if err != nil {
+		t.Fatal(err)
+	}
+	// noinspection GoUnhandledErrorResult
+	defer s.Close()
+	list, err := s.List()
+	if err != nil || len(list) != 1 || !list[0].HasTag("baseline") {
+		t.Fatalf("List after reopen = %v, %v", list, err)
+	}
+	info, err := s.LoadDirInfo(list[0], "/data", true)
+	if err != nil || info.Size != 5 || !info.FileMap["/data"].IsDir {
+		t.Errorf("LoadDirInfo after reopen = %+v, %v", info, err)
+	}
+}
+
+func TestCopyStore(t *testing.T) {
+	dir := t.TempDir()
+	src, err := store.NewFolderStore(dir, "gzip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for i := 0; i < 3; i++ {
+		start := time.Date(2021, 3, i+1, 12, 0, 0, 0, time.Local)
+		snap := store.Snapshot{StartTime: start, ID: store.NewSnapshotID(start)}
+		ids = append(ids, snap.ID)
+		if err := src.Save(snap); err != nil {
+			t.Fatal(err)
+		}
+		info := store.DirInfo{Path: "/data", Size: int64(i)}
+		if i > 0 {
+			info.FileMap = map[string]store.FileInfo{"/data": {IsDir: true, Size: int64(i)}}
+		}
+		if err := src.SaveDirInfo(snap, info); err != nil {
+			t.Fatal(err)
+		}
+		if err := src.SaveDirInfo(snap, store.DirInfo{Path: "/other"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dst, err := store.OpenBoltStore(filepath.Join(dir, "history.db"), dir, "zstd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// noinspection GoUnhandledErrorResult
+	defer dst.Close()
+	if err := dst.Save(store.Snapshot{StartTime: time.Date(2021, 3, 1, 12, 0, 0, 0, time.Local), ID: ids[0]}); err != nil {
+		t.Fatal(err)
+	}
+
+	copied, err := store.CopyStore(dst, src, []string{"/data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != 2 || copied[0] != ids[1] || copied[1] != ids[2] {
+		t.Errorf("CopyStore copied %v; want %v (the first snapshot is already there)", copied, ids[1:])
+	}
+	history, err := dst.History("/data")
+	if err != nil || len(history) != 2 {
+		t.Fatalf("History of the copy = %d entries, %v", len(history), err)
+	}
+	info, err := dst.LoadDirInfo(store.Snapshot{ID: ids[2]}, "/data", true)
+	if err != nil || info.Size != 2 || info.FileMap["/data"].Size != 2 {
+		t.Errorf("LoadDirInfo of the copy = %+v, %v", info, err)
+	}
+	if history, _ := dst.History("/other"); len(history) != 0 {
+		t.Errorf("paths which are not requested are copied: %d entries", len(history))
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/xml"
+	"space-monitor/libs/svgchart"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSvgChart(t *testing.T) {
+	chart := svgchart.Chart{Title: "free <space>", Width: 400, Height: 120}
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	points := []svgchart.Point{{Time: start, Value: 10}, {Time: start.Add(time.Hour), Value: 20}, {Time: start.Add(2 * time.Hour), Value: 15}}
+
+	svg := chart.Render(points)
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Fatalf("invalid svg: %v\n%s", err, svg)
+	}
+	if !strings.Contains(svg, "free &lt;space&gt;") || strings.Count(svg, "<circle") != 3 {
+		t.Errorf("unexpected svg: %s", svg)
+	}
+	// points are spread over the plot width: min value at the bottom, max value at the top
+	if !strings.Contains(svg, `points="64.0,98.0 226.0,24.0 388.0,61.0"`) {
+		t.Errorf("unexpected polyline: %s", svg)
+	}
+	if chart.Render(points[:1]) != "" {
+		t.Errorf("single point chart must be empty")
+	}
+}
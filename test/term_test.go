@@ -0,0 +1,33 @@
+package main
+
+import (
+	"bufio"
+	"space-monitor/libs/term"
+	"strings"
+	"testing"
+)
+
+func TestReadKey(t *testing.T) {
+	in := bufio.NewReader(strings.NewReader("j\x1b[A\x1b[6~\rq\x7f\x1bOD"))
+	want := []term.Key{
+		{Code: term.Rune, Rune: 'j'},
+		{Code: term.Up},
+		{Code: term.PageDown},
+		{Code: term.Enter},
+		{Code: term.Rune, Rune: 'q'},
+		{Code: term.Backspace},
+		{Code: term.Left},
+	}
+	for i, w := range want {
+		key, err := term.ReadKey(in)
+		if err != nil {
+			t.Fatalf("key %d: %v", i, err)
+		}
+		if key != w {
+			t.Errorf("key %d = %+v; want %+v", i, key, w)
+		}
+	}
+	if _, err := term.ReadKey(in); err == nil {
+		t.Errorf("expected EOF")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/xml"
+	"math"
+	"space-monitor/libs/store"
+	"space-monitor/libs/treemap"
+	"strings"
+	"testing"
+)
+
+func TestSquarify(t *testing.T) {
+	// the example from the paper: 6x4 rectangle
+	sizes := []float64{6, 6, 4, 3, 2, 2, 1}
+	rects := treemap.Squarify(sizes, treemap.Rect{W: 6, H: 4})
+	if len(rects) != len(sizes) {
+		t.Fatalf("got %d rects; want %d", len(rects), len(sizes))
+	}
+	for i, r := range rects {
+		if area := r.W * r.H; math.Abs(area-sizes[i]) > 1e-9 {
+			t.Errorf("rect %d area = %v; want %v", i, area, sizes[i])
+		}
+		if r.X < 0 || r.Y < 0 || r.X+r.W > 6+1e-9 || r.Y+r.H > 4+1e-9 {
+			t.Errorf("rect %d %+v is out of bounds", i, r)
+		}
+	}
+	if r := rects[0]; r.X != 0 || r.Y != 0 || math.Abs(r.W-3) > 1e-9 || math.Abs(r.H-2) > 1e-9 {
+		t.Errorf("first rect = %+v; want 3x2 at the corner", r)
+	}
+}
+
+func TestTreemapRender(t *testing.T) {
+	root := &treemap.Node{Name: "root", Size: 3, Children: []*treemap.Node{
+		{Name: "a <b>", Title: "a", Size: 2, Color: "#f00"},
+		{Name: "c", Title: "c", Size: 1},
+	}}
+	svg := treemap.Render(root, 300, 200, 16)
+	if err := xml.Unmarshal([]byte(svg), new(struct{})); err != nil {
+		t.Fatalf("invalid svg: %v", err)
+	}
+	if strings.Count(svg, "<rect") != 3 || !strings.Contains(svg, "a &lt;b&gt;") {
+		t.Errorf("unexpected svg: %s", svg)
+	}
+}
+
+func TestGrowthColor(t *testing.T) {
+	tests := []struct {
+		name           string
+		size, prevSize int64
+		existed        bool
+		want           string
+	}{
+		{name: "new", size: 100, want: "#e04f4f"},
+		{name: "unchanged", size: 100, prevSize: 100, existed: true, want: "#d9d9d9"},
+		{name: "grew by 10%", size: 110, prevSize: 100, existed: true, want: "#dba2a2"},
+		{name: "grew by 25%", size: 125, prevSize: 100, existed: true, want: "#e04f4f"},
+		{name: "grew by 300%", size: 400, prevSize: 100, existed: true, want: "#e04f4f"},
+		{name: "grew from empty", size: 1, prevSize: 0, existed: true, want: "#e04f4f"},
+		{name: "shrunk by 5%", size: 95, prevSize: 100, existed: true, want: "#bed1be"},
+		{name: "shrunk by 50%", size: 50, prevSize: 100, existed: true, want: "#4fb04f"},
+	}
+	for _, test := range tests {
+		if got := treemap.GrowthColor(test.size, test.prevSize, test.existed); got != test.want {
+			t.Errorf("%s: GrowthColor(%d, %d, %v) = %s; want %s", test.name, test.size, test.prevSize, test.existed, got, test.want)
+		}
+	}
+}
+
+func TestTreemapBuild(t *testing.T) {
+	fileMap := map[string]store.FileInfo{
+		"/data":            {IsDir: true, Size: 1130},
+		"/data/big":        {Size: 800},
+		"/data/sub":        {IsDir: true, Size: 310},
+		"/data/sub/a":      {Size: 300},
+		"/data/sub/b":      {Size: 10},
+		"/data/tiny1":      {Size: 12},
+		"/data/tiny2":      {Size: 8},
+		"/data/sub/deep":   {IsDir: true},
+		"/data/sub/deep/x": {},
+	}
+	prevMap := map[string]store.FileInfo{"/data": {IsDir: true, Size: 1000}, "/data/big": {Size: 800}, "/data/sub": {IsDir: true, Size: 200}}
+	identity := func(path string) string { return path }
+
+	root := treemap.Build(fileMap, prevMap, "/data", 1, 50, identity)
+	if root.Name != "data" || root.Size != 1130 || root.Title != "/data\n1.1K (+130B)" || root.Color != treemap.GrowthColor(1130, 1000, true) {
+		t.Errorf("root = %+v", *root)
+	}
+	// children sorted by path, entries below the minimal size merged (not expanded beyond the depth)
+	var got []string
+	for _, child := range root.Children {
+		got = append(got, child.Name)
+		if len(child.Children) > 0 {
+			t.Errorf("%s is expanded beyond the depth", child.Name)
+		}
+	}
+	if strings.Join(got, ",") != "big,sub,(2 small)" {
+		t.Errorf("children = %v", got)
+	}
+	if big := root.Children[0]; big.Color != "#d9d9d9" || big.Title != "/data/big\n800B" {
+		t.Errorf("unchanged child = %+v", *big)
+	}
+	if small := root.Children[2]; small.Size != 20 || small.Title != "2 entries smaller than 50B\n20B" {
+		t.Errorf("small cell = %+v", *small)
+	}
+
+	// deeper levels, new entries and no comparison
+	root = treemap.Build(fileMap, prevMap, "/data/sub", 2, 0, identity)
+	if len(root.Children) != 3 || root.Children[0].Name != "a" || root.Children[0].Title != "/data/sub/a\n300B (new)" || root.Children[0].Color != "#e04f4f" {
+		t.Errorf("subtree = %+v", root.Children)
+	}
+	if deep := root.Children[2]; deep.Name != "deep" || len(deep.Children) != 1 {
+		t.Errorf("deep = %+v", *deep)
+	}
+	root = treemap.Build(fileMap, nil, "/data", 0, 0, identity)
+	if root.Color != "#d9d9d9" || len(root.Children) != 0 {
+		t.Errorf("without comparison: %+v", *root)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"math"
+	"space-monitor/libs/trend"
+	"testing"
+	"time"
+)
+
+func TestTrend(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	points := []trend.Point{
+		{Time: start, Value: 1000},
+		{Time: start.Add(day), Value: 900},
+		{Time: start.Add(2 * day), Value: 800},
+		{Time: start.Add(4 * day), Value: 600},
+	}
+	perDay := func(rate float64) float64 { return rate * day.Seconds() }
+
+	slope, ok := trend.Linear(points)
+	if !ok || math.Abs(perDay(slope)+100) > 1e-6 {
+		t.Errorf("Linear() = %v, %v; want -100/day", perDay(slope), ok)
+	}
+	rate, ok := trend.EWMA(points, 0.5)
+	if !ok || math.Abs(perDay(rate)+100) > 1e-6 {
+		t.Errorf("EWMA() = %v, %v; want -100/day", perDay(rate), ok)
+	}
+	if _, ok := trend.Linear(points[:1]); ok {
+		t.Errorf("Linear() of a single point must fail")
+	}
+
+	left, ok := trend.TimeToZero(600, slope)
+	if !ok || left != 6*day {
+		t.Errorf("TimeToZero() = %v, %v; want %v", left, ok, 6*day)
+	}
+	if _, ok := trend.TimeToZero(600, 0); ok {
+		t.Errorf("TimeToZero() with zero rate must fail")
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"space-monitor/libs/webhook"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	payload := webhook.Payload{
+		Title:     "HOST",
+		Level:     "WARNING",
+		FreeSpace: 10 << 30,
+		FreeText:  "10.0G",
+		Dirs:      []webhook.Dir{{Path: "/var", Size: 5 << 20, DeltaSize: 1 << 20, SizeText: "5.0M", DeltaText: "+1.0M"}},
+		Alerts:    []webhook.Alert{{Level: "WARNING", Rule: "var", Message: "size of /var is above 1M"}},
+	}
+
+	var bodies []string
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	client := webhook.Client{URL: server.URL, Retries: 2, Backoff: time.Millisecond}
+	if err := client.Send(payload); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("attempts = %d; want 3", attempts)
+	}
+	var received webhook.Payload
+	if err := json.Unmarshal([]byte(bodies[0]), &received); err != nil || received.Alerts[0].Rule != "var" {
+		t.Errorf("unexpected json body %s (%v)", bodies[0], err)
+	}
+	if strings.Contains(bodies[0], "10.0G") {
+		t.Errorf("formatted values are in the json body %s", bodies[0])
+	}
+
+	attempts = 0
+	client = webhook.Client{URL: server.URL, Template: "slack", Retries: 1, Backoff: time.Millisecond}
+	if err := client.Send(payload); err == nil {
+		t.Errorf("Send() must fail after all retries")
+	}
+	if err := client.Send(payload); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	var slack struct{ Text string }
+	if err := json.Unmarshal([]byte(bodies[1]), &slack); err != nil || !strings.Contains(slack.Text, "size of /var is above 1M") {
+		t.Errorf("unexpected slack body %s (%v)", bodies[1], err)
+	}
+	if !strings.Contains(slack.Text, "10.0G free") || !strings.Contains(slack.Text, "`/var` 5.0M (+1.0M)") {
+		t.Errorf("slack text has no formatted sizes: %s", slack.Text)
+	}
+
+	unknown := payload
+	unknown.Level = "UNKNOWN"
+	if body, err := (webhook.Client{Template: "slack"}).Body(unknown); err != nil || !strings.Contains(string(body), ":grey_question: *HOST* UNKNOWN") {
+		t.Errorf("slack body of the unknown level = %s, %v", body, err)
+	}
+
+	custom := webhook.Client{Template: `{{ range .Dirs }}{{ .Path }} {{ .SizeText }}{{ end }}`}
+	if body, err := custom.Body(payload); err != nil || string(body) != "/var 5.0M" {
+		t.Errorf("custom template body = %q, %v", body, err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"space-monitor/libs/fmt2"
+	"space-monitor/libs/human"
+	"space-monitor/libs/treemap"
+)
+
+// CommandTreemap renders treemap SVG of the directory from the detailed file map of the snapshot,
+// colored by growth since the comparison snapshot (-against, the previous snapshot by default)
+func CommandTreemap(args []string) int {
+	if len(args) < 1 || len(args) > 2 {
+		LogErr("usage: treemap <path> [snapshot] [-against snapshot] [-depth 3] [-min-size 1M] [-o treemap.svg]")
+		return 1
+	}
+	path, err := filepath.Abs(AbsPath(args[0]))
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	dir, ok := FindDirSettings(path)
+	if !ok {
+		LogErr(path, "is not inside any configured directory")
+		return 1
+	}
+	ref := "last"
+	if len(args) == 2 {
+		ref = args[1]
+	}
+	snapshot, err := FindSnapshot(ref)
+	if err != nil {
+		LogErr(err)
+		return 1
+	}
+	info, err := gStore.LoadDirInfo(snapshot, AbsPath(dir.Path), true)
+	if err != nil {
+		LogErr("no detailed file map:", err)
+		return 1
+	}
+	if _, ok := info.FileMap[path]; !ok {
+		LogErr(path, "is not found in snapshot", snapshot.ID)
+		return 1
+	}
+
+	// comparison snapshot
+	var prevMap map[string]GobFileInfo
+	var against SnapshotStruct
+	if *gAgainst != "" {
+		against, err = FindSnapshot(*gAgainst)
+	} else {
+		against, err = snapshotBefore(snapshot)
+	}
+	if err == nil {
+		if prevInfo, err := gStore.LoadDirInfo(against, AbsPath(dir.Path), true); err == nil {
+			prevMap = prevInfo.FileMap
+		}
+	}
+	if prevMap == nil {
+		gLogger.Println("treemap: no comparison snapshot, growth is not colored")
+	}
+
+	var minSize int64
+	if *gMinSize != "" {
+		if minSize, err = human.ParseSize(*gMinSize); err != nil {
+			LogErr(err)
+			return 1
+		}
+	} else {
+		minSize = info.FileMap[path].Size / 1000 // 0.1% of the total by default
+	}
+	root := treemap.Build(info.FileMap, prevMap, path, *gDepth, minSize, shorifyPath)
+	root.Name = fmt.Sprintf("%s - %s, %s", shorifyPath(path), HumanSize(info.FileMap[path].Size), snapshot.StartTime.Format("02 Jan 2006 15:04"))
+	svg := treemap.Render(root, 1200, 800, 16)
+
+	if *gOutput == "" {
+		fmt2.Println(svg)
+		return 0
+	}
+	if err := os.WriteFile(*gOutput, []byte(svg), 0666); err != nil {
+		LogErr(err)
+		return 1
+	}
+	fmt2.Println("treemap saved to", *gOutput)
+	return 0
+}
+
+// snapshotBefore returns the snapshot preceding the given one
+func snapshotBefore(snapshot SnapshotStruct) (SnapshotStruct, error) {
+	list, err := ListSnapshots()
+	if err != nil {
+		return SnapshotStruct{}, err
+	}
+	for i := len(list) - 1; i > 0; i-- {
+		if list[i].ID == snapshot.ID {
+			return list[i-1], nil
+		}
+	}
+	return SnapshotStruct{}, fmt.Errorf("no snapshot before %s", snapshot.ID)
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/fatih/color"
+	"os"
+	"os/signal"
+	"space-monitor/libs/human"
+	"space-monitor/libs/term"
+	"strings"
+	"time"
+)
+
+// watchCycle is the result of the watch scan cycle
+type watchCycle struct {
+	prev, curr SnapshotStruct
+	trends     Trends
+	alerts     []Alert
+	elapsed    time.Duration
+	err        error
+}
+
+// runWatchCycle scans the directories and compares them against the previous snapshot
+func runWatchCycle() watchCycle {
+	var cycle watchCycle
+	start := time.Now()
+	prev, err := LoadPrevSnapshot(0)
+	if err == nil {
+		loadSnapshotDirs(&prev)
+	}
+	cycle.curr, cycle.err = ScanSnapshot(prev, time.Now(), nil, nil)
+	cycle.elapsed = time.Since(start)
+	if cycle.err != nil {
+		return cycle
+	}
+	if prev.ID == "" {
+		prev.InfoList = make([]DirInfoStruct, len(cycle.curr.InfoList)) // first run
+	}
+	cycle.prev = prev
+	cycle.trends = NewTrends(cycle.curr)
+	cycle.alerts = EvaluateAlerts(gCfg.Alerts, prev, cycle.curr, cycle.trends)
+	if !*gNoSave {
+		DeleteOldSnapshots()
+	}
+	return cycle
+}
+
+// WatchScreen returns the dashboard lines: status line, summary table and alerts
+func WatchScreen(cycle, lastCycle *watchCycle, status string, width, height int) []string {
+	lines := []string{color.New(color.Bold).Sprint(" SPACE MONITOR ") + ColorPale("%s", fitString(status, width-15))}
+	if cycle != nil {
+		if cycle.err != nil {
+			lines = append(lines, color.RedString(" %s", cycle.err))
+		} else {
+			var last *SnapshotStruct
+			if lastCycle != nil && lastCycle.err == nil {
+				last = &lastCycle.curr
+			}
+			tableWriter := SummaryTable(cycle.prev, cycle.curr, last, cycle.elapsed, cycle.trends)
+			tableWriter.SetAllowedRowLength(width)
+			lines = append(lines, strings.Split(tableWriter.Render(), "\n")...)
+			for _, alert := range cycle.alerts {
+				lines = append(lines, fitString(fmt.Sprintf(" %-8s %s: %s", alert.Level, alert.Rule, alert.Message), width))
+			}
+		}
+	}
+	if len(lines) > height {
+		lines = lines[:height]
+	}
+	return lines
+}
+
+// CommandWatch rescans the directories every -interval and redraws the summary table in place
+func CommandWatch() int {
+	interval, err := human.ParseDuration(*gInterval)
+	if err != nil || interval <= 0 {
+		LogErr("invalid interval:", *gInterval)
+		return 1
+	}
+	fd := int(os.Stdout.Fd())
+	if _, _, err := term.Size(fd); err != nil {
+		LogErr("watch needs an interactive terminal:", err)
+		return 1
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	out.WriteString("\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer func() {
+		out.WriteString("\x1b[?25h\x1b[?1049l")
+		out.Flush()
+	}()
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	resize := make(chan os.Signal, 1)
+	notifyResize(resize)
+
+	var cycle, lastCycle *watchCycle
+	done := make(chan watchCycle)
+	scanning := true
+	nextScan := time.Now()
+	go func() { done <- runWatchCycle() }()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		status := ""
+		if scanning {
+			status = gProgress.String()
+		} else {
+			status = fmt.Sprintf("every %s, next scan in %s (ctrl+c to quit)", interval, time.Until(nextScan).Round(time.Second))
+		}
+		width, height, err := term.Size(fd)
+		if err != nil {
+			width, height = 80, 24
+		}
+		out.WriteString("\x1b[H")
+		out.WriteString(strings.Join(WatchScreen(cycle, lastCycle, status, width, height), "\x1b[K\r\n"))
+		out.WriteString("\x1b[K\x1b[J") // clear the rest of the screen
+		out.Flush()
+
+		select {
+		case result := <-done:
+			lastCycle, cycle = cycle, &result
+			scanning = false
+			nextScan = time.Now().Add(interval)
+		case <-ticker.C:
+			if !scanning && time.Now().After(nextScan) {
+				scanning = true
+				go func() { done <- runWatchCycle() }()
+			}
+		case <-resize:
+		case <-interrupt:
+			return 0
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// notifyResize does nothing: there is no resize signal on Windows, the screen is redrawn every second anyway
+func notifyResize(c chan os.Signal) {}